@@ -0,0 +1,583 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/model/user"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	// Store is the product backend: "postgres" or "memory".
+	Store    string
+	HTTP     HTTP
+	Database Database
+	Log      Log
+	Tracing  Tracing
+	Auth     Auth
+	OIDC     OIDC
+}
+
+type HTTP struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout is how long in-flight requests may take to finish
+	// once the server is asked to stop.
+	ShutdownTimeout time.Duration
+}
+
+type Database struct {
+	User     string
+	Password string
+	Name     string
+	Host     string
+	Port     int
+	SSLMode  string
+	// Timeout bounds every query; zero leaves only the request's deadline.
+	Timeout time.Duration
+	// MaxOpenConns and MaxIdleConns size the connection pool; zero open
+	// connections means no limit.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// ConnectAttempts is how many times startup pings the database, waiting
+	// ConnectBackoff after the first failure and twice as long after each
+	// one that follows.
+	ConnectAttempts int
+	ConnectBackoff  time.Duration
+}
+
+type Log struct {
+	Format string
+	Level  string
+}
+
+type Tracing struct {
+	// Exporter is where finished spans go: "none", "stdout" or "otlp".
+	Exporter string
+	// Endpoint is the OTLP/HTTP traces URL the otlp exporter posts to.
+	Endpoint    string
+	ServiceName string
+}
+
+type Auth struct {
+	// SessionKey signs session cookies. Left empty, a random key is made at
+	// startup and every session ends when the server restarts.
+	SessionKey string
+	SessionTTL time.Duration
+	// AdminUser and AdminPassword, when both set, name a user created at
+	// startup unless it already exists.
+	AdminUser     string
+	AdminPassword string
+}
+
+// OIDC configures single sign-on through an OpenID Connect provider.
+type OIDC struct {
+	// Issuer is the provider's URL, where discovery starts. Left empty,
+	// single sign-on is off.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is this server's /login/oidc/callback as browsers reach it.
+	RedirectURL string
+	// RoleClaim names the ID token claim listing the user's groups. Roles
+	// maps its values to store roles, the most trusted match winning; users
+	// with no match get DefaultRole, or are refused when it is empty.
+	RoleClaim   string
+	Roles       map[string]string
+	DefaultRole string
+}
+
+// minSessionKeyLength is the shortest session key accepted, in bytes.
+const minSessionKeyLength = 32
+
+// Default is the configuration used for every setting no source mentions.
+func Default() Config {
+	return Config{
+		Store: "postgres",
+		HTTP: HTTP{
+			Addr:              ":4444",
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: Database{
+			Host:            "localhost",
+			Port:            5432,
+			SSLMode:         "require",
+			Timeout:         5 * time.Second,
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnectAttempts: 5,
+			ConnectBackoff:  500 * time.Millisecond,
+		},
+		Log: Log{
+			Format: "text",
+			Level:  "info",
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			Endpoint:    "http://localhost:4318/v1/traces",
+			ServiceName: "mock-store",
+		},
+		Auth: Auth{
+			SessionTTL: 12 * time.Hour,
+		},
+		OIDC: OIDC{
+			RoleClaim: "groups",
+		},
+	}
+}
+
+// setting ties one field of Config to the names it has in each source: its
+// key in the config file, which is also its flag name, and its env var.
+type setting struct {
+	key   string
+	env   string
+	usage string
+	value flag.Value
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"store", "STORE_BACKEND", "product backend: postgres or memory", (*stringValue)(&c.Store)},
+		{"http.addr", "HTTP_ADDR", "address to listen on", (*stringValue)(&c.HTTP.Addr)},
+		{"http.read_timeout", "HTTP_READ_TIMEOUT", "time allowed to read a request", (*durationValue)(&c.HTTP.ReadTimeout)},
+		{"http.read_header_timeout", "HTTP_READ_HEADER_TIMEOUT", "time allowed to read request headers", (*durationValue)(&c.HTTP.ReadHeaderTimeout)},
+		{"http.write_timeout", "HTTP_WRITE_TIMEOUT", "time allowed to write a response", (*durationValue)(&c.HTTP.WriteTimeout)},
+		{"http.idle_timeout", "HTTP_IDLE_TIMEOUT", "how long idle keep-alive connections stay open", (*durationValue)(&c.HTTP.IdleTimeout)},
+		{"http.shutdown_timeout", "SHUTDOWN_TIMEOUT", "how long to drain requests on shutdown", (*durationValue)(&c.HTTP.ShutdownTimeout)},
+		{"database.user", "POSTGRES_USER", "database user", (*stringValue)(&c.Database.User)},
+		{"database.password", "POSTGRES_PASSWORD", "database password", (*stringValue)(&c.Database.Password)},
+		{"database.name", "POSTGRES_NAME", "database name", (*stringValue)(&c.Database.Name)},
+		{"database.host", "POSTGRES_HOST", "database host", (*stringValue)(&c.Database.Host)},
+		{"database.port", "POSTGRES_PORT", "database port", (*intValue)(&c.Database.Port)},
+		{"database.sslmode", "POSTGRES_SSLMODE", "database sslmode", (*stringValue)(&c.Database.SSLMode)},
+		{"database.timeout", "DB_TIMEOUT", "deadline for each query", (*durationValue)(&c.Database.Timeout)},
+		{"database.max_open_conns", "POSTGRES_MAX_OPEN_CONNS", "most open connections, 0 for no limit", (*intValue)(&c.Database.MaxOpenConns)},
+		{"database.max_idle_conns", "POSTGRES_MAX_IDLE_CONNS", "most idle connections kept in the pool", (*intValue)(&c.Database.MaxIdleConns)},
+		{"database.conn_max_lifetime", "POSTGRES_CONN_MAX_LIFETIME", "how long a connection may be reused, 0 for ever", (*durationValue)(&c.Database.ConnMaxLifetime)},
+		{"database.connect_attempts", "POSTGRES_CONNECT_ATTEMPTS", "how many times to ping the database at startup", (*intValue)(&c.Database.ConnectAttempts)},
+		{"database.connect_backoff", "POSTGRES_CONNECT_BACKOFF", "wait after the first failed ping, doubled each retry", (*durationValue)(&c.Database.ConnectBackoff)},
+		{"log.format", "LOG_FORMAT", "log format: text or json", (*stringValue)(&c.Log.Format)},
+		{"log.level", "LOG_LEVEL", "lowest level logged: debug, info, warn or error", (*stringValue)(&c.Log.Level)},
+		{"tracing.exporter", "TRACING_EXPORTER", "span exporter: none, stdout or otlp", (*stringValue)(&c.Tracing.Exporter)},
+		{"tracing.endpoint", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTLP/HTTP traces URL", (*stringValue)(&c.Tracing.Endpoint)},
+		{"tracing.service_name", "OTEL_SERVICE_NAME", "service name recorded on spans", (*stringValue)(&c.Tracing.ServiceName)},
+		{"auth.session_key", "SESSION_KEY", "secret of at least 32 bytes signing session cookies", (*stringValue)(&c.Auth.SessionKey)},
+		{"auth.session_ttl", "SESSION_TTL", "how long a login lasts", (*durationValue)(&c.Auth.SessionTTL)},
+		{"auth.admin_user", "ADMIN_USER", "user to create at startup", (*stringValue)(&c.Auth.AdminUser)},
+		{"auth.admin_password", "ADMIN_PASSWORD", "password of the startup user", (*stringValue)(&c.Auth.AdminPassword)},
+		{"oidc.issuer", "OIDC_ISSUER", "OpenID Connect provider URL, empty to turn single sign-on off", (*stringValue)(&c.OIDC.Issuer)},
+		{"oidc.client_id", "OIDC_CLIENT_ID", "client id registered with the provider", (*stringValue)(&c.OIDC.ClientID)},
+		{"oidc.client_secret", "OIDC_CLIENT_SECRET", "client secret registered with the provider", (*stringValue)(&c.OIDC.ClientSecret)},
+		{"oidc.redirect_url", "OIDC_REDIRECT_URL", "URL of /login/oidc/callback on this server", (*stringValue)(&c.OIDC.RedirectURL)},
+		{"oidc.role_claim", "OIDC_ROLE_CLAIM", "ID token claim holding the user's groups", (*stringValue)(&c.OIDC.RoleClaim)},
+		{"oidc.roles", "OIDC_ROLES", "group=role pairs, comma separated", (*mapValue)(&c.OIDC.Roles)},
+		{"oidc.default_role", "OIDC_DEFAULT_ROLE", "role of users in no mapped group, empty to refuse them", (*stringValue)(&c.OIDC.DefaultRole)},
+	}
+}
+
+// Load builds the configuration from, in increasing order of precedence:
+// the defaults, the YAML file named by -config or CONFIG_FILE, the .env file
+// named by -env-file (".env" by default, skipped when missing), the
+// environment and the command-line flags in args. It returns the arguments
+// left after the flags.
+//
+// Every problem found is reported at once in an *Error.
+func Load(args []string) (Config, []string, error) {
+	cfg := Default()
+	settings := cfg.settings()
+	problems := &Error{}
+
+	flags, fs, err := parseFlags(settings, args)
+	if err != nil {
+		return cfg, nil, err
+	}
+
+	path := flags["config"]
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadFile(settings, path); err != nil {
+			problems.add(err)
+		}
+	}
+
+	dotenv := map[string]string{}
+	envFile := flags["env-file"]
+	if envFile == "" {
+		envFile = ".env"
+	}
+	if dotenv, err = godotenv.Read(envFile); err != nil {
+		if !errors.Is(err, os.ErrNotExist) || flags["env-file"] != "" {
+			problems.add(err)
+		}
+	}
+
+	for _, s := range settings {
+		raw, ok := os.LookupEnv(s.env)
+		if !ok {
+			raw, ok = dotenv[s.env]
+		}
+		if ok {
+			problems.set(s, s.env, raw)
+		}
+	}
+
+	for _, s := range settings {
+		if raw, ok := flags[s.key]; ok {
+			problems.set(s, "-"+s.key, raw)
+		}
+	}
+
+	problems.Problems = append(problems.Problems, cfg.validate()...)
+	if len(problems.Problems) > 0 {
+		return cfg, fs.Args(), problems
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+// parseFlags collects the flags in args without applying them yet, since
+// they must override sources that are read later.
+func parseFlags(settings []setting, args []string) (map[string]string, *flag.FlagSet, error) {
+	seen := map[string]string{}
+	fs := flag.NewFlagSet("mock-store", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.Var(recorder{seen, "config"}, "config", "YAML configuration file")
+	fs.Var(recorder{seen, "env-file"}, "env-file", "dotenv file, .env by default")
+	for _, s := range settings {
+		fs.Var(recorder{seen, s.key}, s.key, s.usage+" ("+s.env+")")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, fs, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return seen, fs, nil
+}
+
+// loadFile applies the settings found in a YAML file such as
+//
+//	http:
+//	  addr: ":8080"
+//	database:
+//	  timeout: 2s
+//	oidc:
+//	  roles:
+//	    store-admins: admin
+func loadFile(settings []setting, path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	tree := map[string]interface{}{}
+	if err = yaml.Unmarshal(raw, &tree); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	maps := map[string]bool{}
+	for _, s := range settings {
+		if _, ok := s.value.(*mapValue); ok {
+			maps[s.key] = true
+		}
+	}
+
+	values := map[string]string{}
+	flatten("", tree, maps, values)
+
+	problems := &Error{}
+	known := map[string]bool{}
+	for _, s := range settings {
+		known[s.key] = true
+		if v, ok := values[s.key]; ok {
+			problems.set(s, path+": "+s.key, v)
+		}
+	}
+	for key := range values {
+		if !known[key] {
+			problems.Problems = append(problems.Problems, fmt.Sprintf("%s: %s is not a known setting", path, key))
+		}
+	}
+
+	if len(problems.Problems) > 0 {
+		return problems
+	}
+
+	return nil
+}
+
+// flatten turns the sections of tree into dotted keys. A mapping under a
+// key in maps is the value of that setting, in the form mapValue reads.
+func flatten(prefix string, tree map[string]interface{}, maps map[string]bool, out map[string]string) {
+	for k, v := range tree {
+		key := prefix + k
+		if sub, ok := v.(map[string]interface{}); ok {
+			if !maps[key] {
+				flatten(key+".", sub, maps, out)
+				continue
+			}
+
+			m := mapValue{}
+			for sk, sv := range sub {
+				m[sk] = fmt.Sprint(sv)
+			}
+			out[key] = m.String()
+			continue
+		}
+		if v == nil {
+			out[key] = ""
+			continue
+		}
+		out[key] = fmt.Sprint(v)
+	}
+}
+
+func (c Config) validate() []string {
+	problems := []string{}
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	switch c.Store {
+	case "postgres":
+		if c.Database.User == "" {
+			problem("database.user (POSTGRES_USER) is required")
+		}
+		if c.Database.Name == "" {
+			problem("database.name (POSTGRES_NAME) is required")
+		}
+		if c.Database.Host == "" {
+			problem("database.host (POSTGRES_HOST) is required")
+		}
+	case "memory":
+	default:
+		problem("store (STORE_BACKEND) must be postgres or memory, not %q", c.Store)
+	}
+
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		problem("database.port (POSTGRES_PORT) must be between 1 and 65535")
+	}
+	switch c.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		problem("database.sslmode (POSTGRES_SSLMODE) is not a Postgres sslmode: %q", c.Database.SSLMode)
+	}
+	if c.Database.Timeout < 0 {
+		problem("database.timeout (DB_TIMEOUT) must not be negative")
+	}
+	if c.Database.MaxOpenConns < 0 {
+		problem("database.max_open_conns (POSTGRES_MAX_OPEN_CONNS) must not be negative")
+	}
+	if c.Database.MaxIdleConns < 0 {
+		problem("database.max_idle_conns (POSTGRES_MAX_IDLE_CONNS) must not be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		problem("database.max_idle_conns (POSTGRES_MAX_IDLE_CONNS) must not exceed database.max_open_conns")
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		problem("database.conn_max_lifetime (POSTGRES_CONN_MAX_LIFETIME) must not be negative")
+	}
+	if c.Database.ConnectAttempts < 1 {
+		problem("database.connect_attempts (POSTGRES_CONNECT_ATTEMPTS) must be at least 1")
+	}
+	if c.Database.ConnectBackoff <= 0 {
+		problem("database.connect_backoff (POSTGRES_CONNECT_BACKOFF) must be positive")
+	}
+
+	if c.HTTP.Addr == "" {
+		problem("http.addr (HTTP_ADDR) is required")
+	}
+	for _, t := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"http.read_timeout (HTTP_READ_TIMEOUT)", c.HTTP.ReadTimeout},
+		{"http.read_header_timeout (HTTP_READ_HEADER_TIMEOUT)", c.HTTP.ReadHeaderTimeout},
+		{"http.write_timeout (HTTP_WRITE_TIMEOUT)", c.HTTP.WriteTimeout},
+		{"http.idle_timeout (HTTP_IDLE_TIMEOUT)", c.HTTP.IdleTimeout},
+		{"http.shutdown_timeout (SHUTDOWN_TIMEOUT)", c.HTTP.ShutdownTimeout},
+	} {
+		if t.d <= 0 {
+			problem("%s must be positive", t.name)
+		}
+	}
+
+	switch c.Log.Format {
+	case "text", "json":
+	default:
+		problem("log.format (LOG_FORMAT) must be text or json, not %q", c.Log.Format)
+	}
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		problem("log.level (LOG_LEVEL) must be debug, info, warn or error, not %q", c.Log.Level)
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if !httpURL(c.Tracing.Endpoint) {
+			problem("tracing.endpoint (OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) must be an http(s) URL, not %q", c.Tracing.Endpoint)
+		}
+	default:
+		problem("tracing.exporter (TRACING_EXPORTER) must be none, stdout or otlp, not %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.ServiceName == "" {
+		problem("tracing.service_name (OTEL_SERVICE_NAME) is required")
+	}
+
+	if c.Auth.SessionKey != "" && len(c.Auth.SessionKey) < minSessionKeyLength {
+		problem("auth.session_key (SESSION_KEY) must be at least %d bytes long", minSessionKeyLength)
+	}
+	if c.Auth.SessionTTL <= 0 {
+		problem("auth.session_ttl (SESSION_TTL) must be positive")
+	}
+	if (c.Auth.AdminUser == "") != (c.Auth.AdminPassword == "") {
+		problem("auth.admin_user (ADMIN_USER) and auth.admin_password (ADMIN_PASSWORD) must be set together")
+	}
+
+	if c.OIDC.Issuer != "" {
+		if !httpURL(c.OIDC.Issuer) {
+			problem("oidc.issuer (OIDC_ISSUER) must be an http(s) URL, not %q", c.OIDC.Issuer)
+		}
+		if c.OIDC.ClientID == "" {
+			problem("oidc.client_id (OIDC_CLIENT_ID) is required for single sign-on")
+		}
+		if !httpURL(c.OIDC.RedirectURL) {
+			problem("oidc.redirect_url (OIDC_REDIRECT_URL) must be an http(s) URL, not %q", c.OIDC.RedirectURL)
+		}
+		if c.OIDC.RoleClaim == "" {
+			problem("oidc.role_claim (OIDC_ROLE_CLAIM) is required for single sign-on")
+		}
+		for group, role := range c.OIDC.Roles {
+			if _, err := user.ParseRole(role); err != nil {
+				problem("oidc.roles (OIDC_ROLES): %s maps to %q, not a role", group, role)
+			}
+		}
+		if c.OIDC.DefaultRole != "" {
+			if _, err := user.ParseRole(c.OIDC.DefaultRole); err != nil {
+				problem("oidc.default_role (OIDC_DEFAULT_ROLE) must be viewer, clerk, manager or admin, not %q", c.OIDC.DefaultRole)
+			}
+		}
+	}
+
+	return problems
+}
+
+func httpURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Error lists every setting that is missing or invalid.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+func (e *Error) add(err error) {
+	var nested *Error
+	if errors.As(err, &nested) {
+		e.Problems = append(e.Problems, nested.Problems...)
+		return
+	}
+
+	e.Problems = append(e.Problems, err.Error())
+}
+
+// set applies raw to s, recording a problem attributed to source on failure.
+func (e *Error) set(s setting, source, raw string) {
+	if err := s.value.Set(raw); err != nil {
+		e.Problems = append(e.Problems, fmt.Sprintf("%s: %v", source, err))
+	}
+}
+
+// recorder is a flag.Value that only remembers what it was given.
+type recorder struct {
+	seen map[string]string
+	key  string
+}
+
+func (r recorder) String() string     { return "" }
+func (r recorder) Set(s string) error { r.seen[r.key] = s; return nil }
+
+type stringValue string
+
+func (v *stringValue) String() string     { return string(*v) }
+func (v *stringValue) Set(s string) error { *v = stringValue(strings.TrimSpace(s)); return nil }
+
+type intValue int
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("%q is not a whole number", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+type durationValue time.Duration
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("%q is not a duration such as 5s or 1m30s", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+// mapValue holds "key=value" pairs separated by commas.
+type mapValue map[string]string
+
+func (v *mapValue) String() string {
+	pairs := []string{}
+	for k, val := range *v {
+		pairs = append(pairs, k+"="+val)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (v *mapValue) Set(s string) error {
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, val, ok := strings.Cut(pair, "=")
+		k, val = strings.TrimSpace(k), strings.TrimSpace(val)
+		if !ok || k == "" || val == "" {
+			return fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		m[k] = val
+	}
+	*v = m
+	return nil
+}
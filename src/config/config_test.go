@@ -0,0 +1,193 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// clearEnv unsets every variable Load reads for the duration of the test.
+func clearEnv(t *testing.T) {
+	t.Helper()
+
+	cfg := Default()
+	names := []string{"CONFIG_FILE"}
+	for _, s := range cfg.settings() {
+		names = append(names, s.env)
+	}
+	for _, name := range names {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+	assert := assert.New(t)
+
+	cfg, args, err := Load([]string{"-store", "memory", "migrate", "up"})
+
+	assert.Nil(err)
+	assert.Equal([]string{"migrate", "up"}, args)
+	want := Default()
+	want.Store = "memory"
+	assert.Equal(want, cfg)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	assert := assert.New(t)
+
+	file := writeFile(t, "config.yaml", `
+http:
+  addr: ":1000"
+  read_timeout: 1s
+  write_timeout: 1s
+database:
+  user: file
+  name: store
+  port: 6000
+  timeout: 1s
+`)
+	dotenv := writeFile(t, ".env", "HTTP_ADDR=:2000\nHTTP_READ_TIMEOUT=2s\nPOSTGRES_PORT=6001\n")
+	t.Setenv("HTTP_ADDR", ":3000")
+
+	cfg, _, err := Load([]string{"-config", file, "-env-file", dotenv, "-http.addr", ":4000"})
+
+	assert.Nil(err)
+	assert.Equal(":4000", cfg.HTTP.Addr, "flags win")
+	assert.Equal(2*time.Second, cfg.HTTP.ReadTimeout, ".env beats the file")
+	assert.Equal(time.Second, cfg.HTTP.WriteTimeout, "the file beats the defaults")
+	assert.Equal(6001, cfg.Database.Port)
+	assert.Equal("file", cfg.Database.User)
+	assert.Equal(time.Second, cfg.Database.Timeout)
+	assert.Equal(Default().HTTP.IdleTimeout, cfg.HTTP.IdleTimeout)
+
+	t.Setenv("CONFIG_FILE", file)
+	cfg, _, err = Load(nil)
+	assert.Nil(err)
+	assert.Equal("file", cfg.Database.User)
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	clearEnv(t)
+	assert := assert.New(t)
+
+	file := writeFile(t, "config.yaml", "http:\n  adr: \":1\"\n")
+	t.Setenv("DB_TIMEOUT", "soon")
+	t.Setenv("POSTGRES_SSLMODE", "d")
+	t.Setenv("LOG_FORMAT", "xml")
+	t.Setenv("TRACING_EXPORTER", "otlp")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "localhost:4318")
+	t.Setenv("SESSION_KEY", "short")
+	t.Setenv("ADMIN_USER", "admin")
+
+	_, _, err := Load([]string{"-config", file, "-database.port", "many"})
+
+	var cfgErr *Error
+	assert.True(errors.As(err, &cfgErr))
+	assert.ElementsMatch([]string{
+		file + ": http.adr is not a known setting",
+		`DB_TIMEOUT: "soon" is not a duration such as 5s or 1m30s`,
+		`-database.port: "many" is not a whole number`,
+		"database.user (POSTGRES_USER) is required",
+		"database.name (POSTGRES_NAME) is required",
+		`database.sslmode (POSTGRES_SSLMODE) is not a Postgres sslmode: "d"`,
+		`log.format (LOG_FORMAT) must be text or json, not "xml"`,
+		`tracing.endpoint (OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) must be an http(s) URL, not "localhost:4318"`,
+		"auth.session_key (SESSION_KEY) must be at least 32 bytes long",
+		"auth.admin_user (ADMIN_USER) and auth.admin_password (ADMIN_PASSWORD) must be set together",
+	}, cfgErr.Problems)
+	assert.Contains(err.Error(), "invalid configuration:\n  ")
+}
+
+func TestLoadFiles(t *testing.T) {
+	clearEnv(t)
+	assert := assert.New(t)
+
+	t.Run("Testing missing default .env is fine", func(t *testing.T) {
+		_, _, err := Load([]string{"-store", "memory"})
+		assert.Nil(err)
+	})
+
+	t.Run("Testing missing named files", func(t *testing.T) {
+		missing := filepath.Join(t.TempDir(), "nope")
+		_, _, err := Load([]string{"-store", "memory", "-config", missing + ".yaml", "-env-file", missing})
+
+		var cfgErr *Error
+		assert.True(errors.As(err, &cfgErr))
+		assert.Len(cfgErr.Problems, 2)
+	})
+
+	t.Run("Testing unknown flag", func(t *testing.T) {
+		_, _, err := Load([]string{"-color", "blue"})
+		assert.ErrorContains(err, "flag provided but not defined: -color")
+	})
+}
+
+func TestLoadOIDC(t *testing.T) {
+	clearEnv(t)
+	assert := assert.New(t)
+
+	t.Setenv("OIDC_ISSUER", "https://id.example.com")
+	t.Setenv("OIDC_CLIENT_ID", "mock-store")
+	t.Setenv("OIDC_REDIRECT_URL", "https://store.example.com/login/oidc/callback")
+	t.Setenv("OIDC_ROLES", "store-admins=admin, store-clerks = Clerk,")
+
+	cfg, _, err := Load([]string{"-store", "memory"})
+
+	assert.Nil(err)
+	assert.Equal("groups", cfg.OIDC.RoleClaim)
+	assert.Equal(map[string]string{"store-admins": "admin", "store-clerks": "Clerk"}, cfg.OIDC.Roles)
+
+	t.Setenv("OIDC_CLIENT_ID", "")
+	t.Setenv("OIDC_REDIRECT_URL", "/login/oidc/callback")
+	t.Setenv("OIDC_ROLES", "store-admins=owner")
+	t.Setenv("OIDC_DEFAULT_ROLE", "guest")
+
+	_, _, err = Load([]string{"-store", "memory", "-oidc.role_claim", ""})
+
+	var cfgErr *Error
+	assert.True(errors.As(err, &cfgErr))
+	assert.ElementsMatch([]string{
+		"oidc.client_id (OIDC_CLIENT_ID) is required for single sign-on",
+		`oidc.redirect_url (OIDC_REDIRECT_URL) must be an http(s) URL, not "/login/oidc/callback"`,
+		"oidc.role_claim (OIDC_ROLE_CLAIM) is required for single sign-on",
+		`oidc.roles (OIDC_ROLES): store-admins maps to "owner", not a role`,
+		`oidc.default_role (OIDC_DEFAULT_ROLE) must be viewer, clerk, manager or admin, not "guest"`,
+	}, cfgErr.Problems)
+
+	_, _, err = Load([]string{"-store", "memory", "-oidc.roles", "admins"})
+	assert.ErrorContains(err, `-oidc.roles: "admins" is not a key=value pair`)
+
+	clearEnv(t)
+	file := writeFile(t, "config.yaml", `
+oidc:
+  issuer: https://id.example.com
+  client_id: mock-store
+  redirect_url: https://store.example.com/login/oidc/callback
+  roles:
+    store-admins: admin
+    store-clerks: clerk
+`)
+	cfg, _, err = Load([]string{"-store", "memory", "-config", file})
+	assert.Nil(err)
+	assert.Equal(map[string]string{"store-admins": "admin", "store-clerks": "clerk"}, cfg.OIDC.Roles, "a YAML mapping")
+
+	file = writeFile(t, "config.yaml", "oidc:\n  roles: \"store-admins=admin\"\n")
+	cfg, _, err = Load([]string{"-store", "memory", "-config", file})
+	assert.Nil(err)
+	assert.Equal(map[string]string{"store-admins": "admin"}, cfg.OIDC.Roles, "the string form")
+}
@@ -0,0 +1,349 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/model/product"
+	"github.com/silastgoes/mock-store/src/money"
+)
+
+type productApiControl struct {
+	productService product.ProductModelService
+	logger         *slog.Logger
+}
+
+//go:generate mockgen --source=api.go --package=mocks --destination=./mocks/api.go  ProductApiControlService
+type ProductApiControlService interface {
+	List(w http.ResponseWriter, r *http.Request)
+	Get(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
+	Replace(w http.ResponseWriter, r *http.Request)
+	Patch(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+	History(w http.ResponseWriter, r *http.Request)
+}
+
+// productRequest is the JSON body accepted by Create, Replace and Patch.
+// Fields are pointers so a PATCH can tell an omitted field from a zero value.
+type productRequest struct {
+	Name        *string       `json:"name"`
+	Description *string       `json:"description"`
+	Value       *priceRequest `json:"value"`
+	Quantity    *int          `json:"quantity"`
+}
+
+// priceRequest is a price as sent, before it is read in a currency: a bare
+// number or string, or an object whose currency may be left out. Either way
+// the product keeps the currency it has.
+type priceRequest struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func (pr *priceRequest) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		type plain priceRequest
+		return json.Unmarshal(data, (*plain)(pr))
+	}
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &pr.Amount)
+	}
+
+	n := json.Number("")
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	pr.Amount = n.String()
+
+	return nil
+}
+
+type apiError struct {
+	Status  int               `json:"status"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+type errorEnvelope struct {
+	Error apiError `json:"error"`
+}
+
+func NewProductApiControl(svr product.ProductModelService, logger *slog.Logger) *productApiControl {
+	return &productApiControl{
+		productService: svr,
+		logger:         logger,
+	}
+}
+
+func (pc *productApiControl) List(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r.URL.Query())
+	if err != nil {
+		pc.writeError(w, r, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	page, err := pc.productService.ListProducts(r.Context(), opts)
+	if err != nil {
+		logFailure(r.Context(), pc.logger, logging.ProductListFailed, err)
+		pc.writeServiceError(w, r, err)
+		return
+	}
+
+	pc.writeJSON(w, r, http.StatusOK, page)
+}
+
+func (pc *productApiControl) Get(w http.ResponseWriter, r *http.Request) {
+	p, ok := pc.find(w, r)
+	if !ok {
+		return
+	}
+
+	pc.writeJSON(w, r, http.StatusOK, p)
+}
+
+func (pc *productApiControl) Create(w http.ResponseWriter, r *http.Request) {
+	req, ok := pc.decodeProduct(w, r)
+	if !ok {
+		return
+	}
+
+	p := product.Product{}
+	if msg := req.missing(); msg != "" {
+		pc.writeError(w, r, http.StatusUnprocessableEntity, msg)
+		return
+	}
+	if err := req.apply(&p); err != nil {
+		pc.writeServiceError(w, r, err)
+		return
+	}
+	if err := p.Validate(); err != nil {
+		pc.writeServiceError(w, r, err)
+		return
+	}
+
+	id, err := pc.productService.Create(r.Context(), p.Name, p.Description, p.Value, p.Quantity)
+	if err != nil {
+		logFailure(r.Context(), pc.logger, logging.ProductCreateFailed, err)
+		pc.writeServiceError(w, r, err)
+		return
+	}
+
+	p.Id = id
+	w.Header().Set("Location", "/api/v1/products/"+strconv.Itoa(id))
+	pc.writeJSON(w, r, http.StatusCreated, p)
+}
+
+func (pc *productApiControl) Replace(w http.ResponseWriter, r *http.Request) {
+	id, ok := pc.productId(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := pc.decodeProduct(w, r)
+	if !ok {
+		return
+	}
+
+	p := product.Product{Id: id}
+	if msg := req.missing(); msg != "" {
+		pc.writeError(w, r, http.StatusUnprocessableEntity, msg)
+		return
+	}
+	if err := req.apply(&p); err != nil {
+		pc.writeServiceError(w, r, err)
+		return
+	}
+
+	pc.save(w, r, p)
+}
+
+func (pc *productApiControl) Patch(w http.ResponseWriter, r *http.Request) {
+	p, ok := pc.find(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := pc.decodeProduct(w, r)
+	if !ok {
+		return
+	}
+
+	if err := req.apply(&p); err != nil {
+		pc.writeServiceError(w, r, err)
+		return
+	}
+
+	pc.save(w, r, p)
+}
+
+func (pc *productApiControl) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pc.productId(w, r)
+	if !ok {
+		return
+	}
+
+	err := pc.productService.Delete(r.Context(), strconv.Itoa(id))
+	if err != nil {
+		logFailure(r.Context(), pc.logger, logging.ProductDeleteFailed, err, "product_id", id)
+		pc.writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// History lists the changes recorded for a product, oldest first.
+func (pc *productApiControl) History(w http.ResponseWriter, r *http.Request) {
+	id, ok := pc.productId(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := pc.productService.History(r.Context(), strconv.Itoa(id))
+	if err != nil {
+		logFailure(r.Context(), pc.logger, logging.ProductAuditFailed, err, "product_id", id)
+		pc.writeServiceError(w, r, err)
+		return
+	}
+
+	pc.writeJSON(w, r, http.StatusOK, entries)
+}
+
+// find loads the product addressed by the last path segment, writing a 404
+// when the id is malformed or unknown.
+func (pc *productApiControl) find(w http.ResponseWriter, r *http.Request) (product.Product, bool) {
+	id, ok := pc.productId(w, r)
+	if !ok {
+		return product.Product{}, false
+	}
+
+	p, err := pc.productService.Get(r.Context(), strconv.Itoa(id))
+	if err != nil {
+		logFailure(r.Context(), pc.logger, logging.ProductGetFailed, err, "product_id", id)
+		pc.writeServiceError(w, r, err)
+		return p, false
+	}
+
+	return p, true
+}
+
+func (pc *productApiControl) save(w http.ResponseWriter, r *http.Request, p product.Product) {
+	if err := p.Validate(); err != nil {
+		pc.writeServiceError(w, r, err)
+		return
+	}
+
+	err := pc.productService.Update(r.Context(), p.Id, p.Name, p.Description, p.Value, p.Quantity)
+	if err != nil {
+		logFailure(r.Context(), pc.logger, logging.ProductUpdateFailed, err, "product_id", p.Id)
+		pc.writeServiceError(w, r, err)
+		return
+	}
+
+	pc.writeJSON(w, r, http.StatusOK, p)
+}
+
+// apply copies the fields sent onto p. A price sent without a currency is
+// read in p's own, or DefaultCurrency for a new product.
+func (req productRequest) apply(p *product.Product) error {
+	if req.Name != nil {
+		p.Name = *req.Name
+	}
+	if req.Description != nil {
+		p.Description = *req.Description
+	}
+	if req.Quantity != nil {
+		p.Quantity = *req.Quantity
+	}
+	if req.Value == nil {
+		return nil
+	}
+
+	currency := req.Value.Currency
+	if currency == "" {
+		currency = p.Value.Currency
+	}
+
+	value, err := money.Parse(req.Value.Amount, currency)
+	if err != nil {
+		errs := product.ValidationErrors{}
+		priceError(errs, err, currency)
+		return errs
+	}
+	p.Value = value
+
+	return nil
+}
+
+// missing reports the first required field absent from a full representation.
+func (req productRequest) missing() string {
+	switch {
+	case req.Name == nil:
+		return "name is required"
+	case req.Value == nil:
+		return "value is required"
+	case req.Quantity == nil:
+		return "quantity is required"
+	}
+
+	return ""
+}
+
+// productId reads the {id} path parameter. Malformed ids cannot name any
+// product, so they are answered with a 404.
+func (pc *productApiControl) productId(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		pc.writeError(w, r, http.StatusNotFound, product.ErrNotFound.Error())
+		return id, false
+	}
+
+	return id, true
+}
+
+func (pc *productApiControl) decodeProduct(w http.ResponseWriter, r *http.Request) (productRequest, bool) {
+	req := productRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		pc.writeError(w, r, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return req, false
+	}
+
+	return req, true
+}
+
+func (pc *productApiControl) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	writeJSON(w, r, pc.logger, status, v)
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, logger *slog.Logger, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.WarnContext(r.Context(), logging.HttpWriteFailed, "error", err)
+	}
+}
+
+func (pc *productApiControl) writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	pc.writeJSON(w, r, status, errorEnvelope{Error: apiError{Status: status, Message: message}})
+}
+
+// writeServiceError answers with the status matching err, listing the
+// broken fields when err is a product.ValidationErrors.
+func (pc *productApiControl) writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	status, message := statusFromError(err)
+	body := apiError{Status: status, Message: message}
+
+	var errs product.ValidationErrors
+	if errors.As(err, &errs) {
+		body.Fields = errs
+	}
+
+	pc.writeJSON(w, r, status, errorEnvelope{Error: body})
+}
@@ -0,0 +1,501 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/model/product"
+	"github.com/silastgoes/mock-store/src/model/product/mocks"
+	"github.com/silastgoes/mock-store/src/money"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRequest is httptest.NewRequest plus the {id} path value the router
+// fills in for /products/{id} routes.
+func newRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i, segment := range segments {
+		if segment == "products" && i+1 < len(segments) && segments[i+1] != "new" {
+			req.SetPathValue("id", segments[i+1])
+		}
+	}
+
+	return req
+}
+
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(v))
+}
+
+func TestApiList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	srv := mocks.NewMockProductModelService(ctrl)
+	pc := NewProductApiControl(srv, logging.Discard())
+
+	t.Run("Testing success result", func(t *testing.T) {
+		min := money.New(150, "BRL")
+		expected := product.Page{
+			Products:   []product.Product{RandonProduct(), RandonProduct()},
+			Total:      9,
+			Limit:      2,
+			NextCursor: "cursor",
+		}
+		srv.EXPECT().ListProducts(gomock.Any(), product.ListOptions{
+			Limit:    2,
+			Sort:     "value",
+			Desc:     true,
+			MinValue: &min,
+			InStock:  true,
+		}).Return(expected, nil)
+
+		w := httptest.NewRecorder()
+		pc.List(w, newRequest(http.MethodGet, "/api/v1/products?limit=2&sort=value&order=desc&min_value=1.5&in_stock=true", nil))
+
+		res := product.Page{}
+		decodeBody(t, w, &res)
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal("application/json", w.Header().Get("Content-Type"))
+		assert.Equal(expected, res)
+	})
+
+	t.Run("Testing currency", func(t *testing.T) {
+		min := money.New(1000, "USD")
+		srv.EXPECT().ListProducts(gomock.Any(), product.ListOptions{Currency: "USD", MinValue: &min}).Return(product.Page{}, nil)
+
+		w := httptest.NewRecorder()
+		pc.List(w, newRequest(http.MethodGet, "/api/v1/products?currency=usd&min_value=10", nil))
+		assert.Equal(http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		pc.List(w, newRequest(http.MethodGet, "/api/v1/products?currency=XYZ", nil))
+		assert.Equal(http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("Testing invalid parameters", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		pc.List(w, newRequest(http.MethodGet, "/api/v1/products?limit=many", nil))
+
+		res := errorEnvelope{}
+		decodeBody(t, w, &res)
+		assert.Equal(http.StatusUnprocessableEntity, w.Code)
+		assert.Equal("invalid product: limit must be an integer", res.Error.Message)
+	})
+
+	t.Run("Testing timeout", func(t *testing.T) {
+		srv.EXPECT().ListProducts(gomock.Any(), gomock.Any()).Return(product.Page{}, context.DeadlineExceeded)
+
+		w := httptest.NewRecorder()
+		pc.List(w, newRequest(http.MethodGet, "/api/v1/products", nil))
+
+		assert.Equal(http.StatusGatewayTimeout, w.Code)
+	})
+
+	t.Run("Testing error", func(t *testing.T) {
+		srv.EXPECT().ListProducts(gomock.Any(), gomock.Any()).Return(product.Page{}, errors.New("boom"))
+
+		w := httptest.NewRecorder()
+		pc.List(w, newRequest(http.MethodGet, "/api/v1/products", nil))
+
+		res := errorEnvelope{}
+		decodeBody(t, w, &res)
+		assert.Equal(http.StatusInternalServerError, w.Code)
+		assert.Equal(http.StatusInternalServerError, res.Error.Status)
+	})
+}
+
+func TestApiGet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	srv := mocks.NewMockProductModelService(ctrl)
+	pc := NewProductApiControl(srv, logging.Discard())
+	expected := RandonProduct()
+
+	t.Run("Testing success result", func(t *testing.T) {
+		srv.EXPECT().Get(gomock.Any(), fmt.Sprint(expected.Id)).Return(expected, nil)
+
+		w := httptest.NewRecorder()
+		pc.Get(w, newRequest(http.MethodGet, "/api/v1/products/"+fmt.Sprint(expected.Id), nil))
+
+		res := product.Product{}
+		decodeBody(t, w, &res)
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal(expected, res)
+	})
+
+	t.Run("Testing not found", func(t *testing.T) {
+		srv.EXPECT().Get(gomock.Any(), "999").Return(product.Product{}, product.ErrNotFound)
+
+		w := httptest.NewRecorder()
+		pc.Get(w, newRequest(http.MethodGet, "/api/v1/products/999", nil))
+
+		res := errorEnvelope{}
+		decodeBody(t, w, &res)
+		assert.Equal(http.StatusNotFound, w.Code)
+		assert.Equal("product not found", res.Error.Message)
+	})
+
+	t.Run("Testing malformed id", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		pc.Get(w, newRequest(http.MethodGet, "/api/v1/products/abc", nil))
+
+		assert.Equal(http.StatusNotFound, w.Code)
+	})
+}
+
+func TestApiCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	srv := mocks.NewMockProductModelService(ctrl)
+	pc := NewProductApiControl(srv, logging.Discard())
+	expected := RandonProduct()
+
+	t.Run("Testing success result", func(t *testing.T) {
+		body, _ := json.Marshal(expected)
+		srv.EXPECT().Create(gomock.Any(), expected.Name, expected.Description, expected.Value, expected.Quantity).Return(expected.Id, nil)
+
+		w := httptest.NewRecorder()
+		pc.Create(w, newRequest(http.MethodPost, "/api/v1/products", strings.NewReader(string(body))))
+
+		res := product.Product{}
+		decodeBody(t, w, &res)
+		assert.Equal(http.StatusCreated, w.Code)
+		assert.Equal("/api/v1/products/"+fmt.Sprint(expected.Id), w.Header().Get("Location"))
+		assert.Equal(expected, res)
+	})
+
+	t.Run("Testing malformed body", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		pc.Create(w, newRequest(http.MethodPost, "/api/v1/products", strings.NewReader("{")))
+
+		assert.Equal(http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Testing missing field", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		pc.Create(w, newRequest(http.MethodPost, "/api/v1/products", strings.NewReader(`{"name":"x","value":1}`)))
+
+		res := errorEnvelope{}
+		decodeBody(t, w, &res)
+		assert.Equal(http.StatusUnprocessableEntity, w.Code)
+		assert.Equal("quantity is required", res.Error.Message)
+	})
+
+	t.Run("Testing invalid field", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		pc.Create(w, newRequest(http.MethodPost, "/api/v1/products", strings.NewReader(`{"name":" ","value":-1,"quantity":1}`)))
+
+		res := errorEnvelope{}
+		decodeBody(t, w, &res)
+		assert.Equal(http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(map[string]string{"name": "is required", "value": "must not be negative"}, res.Error.Fields)
+	})
+
+	t.Run("Testing inexact value", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		pc.Create(w, newRequest(http.MethodPost, "/api/v1/products", strings.NewReader(`{"name":"x","value":0.305,"quantity":1}`)))
+
+		res := errorEnvelope{}
+		decodeBody(t, w, &res)
+		assert.Equal(http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(map[string]string{"value": "must have at most 2 decimal places"}, res.Error.Fields)
+	})
+
+	t.Run("Testing invalid product", func(t *testing.T) {
+		body, _ := json.Marshal(expected)
+		srv.EXPECT().Create(gomock.Any(), expected.Name, expected.Description, expected.Value, expected.Quantity).Return(0, product.ErrInvalid)
+
+		w := httptest.NewRecorder()
+		pc.Create(w, newRequest(http.MethodPost, "/api/v1/products", strings.NewReader(string(body))))
+
+		assert.Equal(http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("Testing error", func(t *testing.T) {
+		body, _ := json.Marshal(expected)
+		srv.EXPECT().Create(gomock.Any(), expected.Name, expected.Description, expected.Value, expected.Quantity).Return(0, errors.New("boom"))
+
+		w := httptest.NewRecorder()
+		pc.Create(w, newRequest(http.MethodPost, "/api/v1/products", strings.NewReader(string(body))))
+
+		assert.Equal(http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestApiReplace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	srv := mocks.NewMockProductModelService(ctrl)
+	pc := NewProductApiControl(srv, logging.Discard())
+	current := RandonProduct()
+	url := "/api/v1/products/" + fmt.Sprint(current.Id)
+
+	t.Run("Testing success result", func(t *testing.T) {
+		srv.EXPECT().Update(gomock.Any(), current.Id, "new", "", money.New(250, "BRL"), 3).Return(nil)
+
+		w := httptest.NewRecorder()
+		pc.Replace(w, newRequest(http.MethodPut, url, strings.NewReader(`{"name":"new","value":2.5,"quantity":3}`)))
+
+		res := product.Product{}
+		decodeBody(t, w, &res)
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal(product.Product{Id: current.Id, Name: "new", Value: money.New(250, "BRL"), Quantity: 3}, res)
+	})
+
+	t.Run("Testing missing field", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		pc.Replace(w, newRequest(http.MethodPut, url, strings.NewReader(`{"name":"new"}`)))
+
+		assert.Equal(http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("Testing not found", func(t *testing.T) {
+		srv.EXPECT().Update(gomock.Any(), current.Id, "new", "", money.New(250, "BRL"), 3).Return(product.ErrNotFound)
+
+		w := httptest.NewRecorder()
+		pc.Replace(w, newRequest(http.MethodPut, url, strings.NewReader(`{"name":"new","value":2.5,"quantity":3}`)))
+
+		assert.Equal(http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Testing conflict", func(t *testing.T) {
+		srv.EXPECT().Update(gomock.Any(), current.Id, "new", "", money.New(250, "BRL"), 3).Return(product.ErrConflict)
+
+		w := httptest.NewRecorder()
+		pc.Replace(w, newRequest(http.MethodPut, url, strings.NewReader(`{"name":"new","value":2.5,"quantity":3}`)))
+
+		res := errorEnvelope{}
+		decodeBody(t, w, &res)
+		assert.Equal(http.StatusConflict, w.Code)
+		assert.Equal(product.ErrConflict.Error(), res.Error.Message)
+	})
+}
+
+func TestApiPatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	srv := mocks.NewMockProductModelService(ctrl)
+	pc := NewProductApiControl(srv, logging.Discard())
+	current := RandonProduct()
+	url := "/api/v1/products/" + fmt.Sprint(current.Id)
+
+	t.Run("Testing success result", func(t *testing.T) {
+		srv.EXPECT().Get(gomock.Any(), fmt.Sprint(current.Id)).Return(current, nil)
+		srv.EXPECT().Update(gomock.Any(), current.Id, current.Name, current.Description, current.Value, 7).Return(nil)
+
+		w := httptest.NewRecorder()
+		pc.Patch(w, newRequest(http.MethodPatch, url, strings.NewReader(`{"quantity":7}`)))
+
+		res := product.Product{}
+		decodeBody(t, w, &res)
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal(7, res.Quantity)
+		assert.Equal(current.Name, res.Name)
+	})
+
+	t.Run("Testing price without currency", func(t *testing.T) {
+		usd := current
+		usd.Value = money.New(1000, "USD")
+		srv.EXPECT().Get(gomock.Any(), fmt.Sprint(current.Id)).Return(usd, nil).Times(2)
+		srv.EXPECT().Update(gomock.Any(), current.Id, current.Name, current.Description, money.New(1250, "USD"), current.Quantity).Return(nil).Times(2)
+
+		for _, body := range []string{`{"value":12.5}`, `{"value":{"amount":"12.50"}}`} {
+			w := httptest.NewRecorder()
+			pc.Patch(w, newRequest(http.MethodPatch, url, strings.NewReader(body)))
+
+			res := product.Product{}
+			decodeBody(t, w, &res)
+			assert.Equal(http.StatusOK, w.Code, body)
+			assert.Equal(money.New(1250, "USD"), res.Value, body)
+		}
+	})
+
+	t.Run("Testing price in another currency", func(t *testing.T) {
+		srv.EXPECT().Get(gomock.Any(), fmt.Sprint(current.Id)).Return(current, nil)
+		srv.EXPECT().Update(gomock.Any(), current.Id, current.Name, current.Description, money.New(1250, "EUR"), current.Quantity).Return(nil)
+
+		w := httptest.NewRecorder()
+		pc.Patch(w, newRequest(http.MethodPatch, url, strings.NewReader(`{"value":{"amount":"12.50","currency":"EUR"}}`)))
+
+		assert.Equal(http.StatusOK, w.Code)
+	})
+
+	t.Run("Testing invalid field", func(t *testing.T) {
+		srv.EXPECT().Get(gomock.Any(), fmt.Sprint(current.Id)).Return(current, nil)
+
+		w := httptest.NewRecorder()
+		pc.Patch(w, newRequest(http.MethodPatch, url, strings.NewReader(`{"name":""}`)))
+
+		assert.Equal(http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("Testing error", func(t *testing.T) {
+		srv.EXPECT().Get(gomock.Any(), fmt.Sprint(current.Id)).Return(current, nil)
+		srv.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("boom"))
+
+		w := httptest.NewRecorder()
+		pc.Patch(w, newRequest(http.MethodPatch, url, strings.NewReader(`{}`)))
+
+		assert.Equal(http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestApiDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	srv := mocks.NewMockProductModelService(ctrl)
+	pc := NewProductApiControl(srv, logging.Discard())
+	current := RandonProduct()
+	url := "/api/v1/products/" + fmt.Sprint(current.Id)
+
+	t.Run("Testing success result", func(t *testing.T) {
+		srv.EXPECT().Delete(gomock.Any(), fmt.Sprint(current.Id)).Return(nil)
+
+		w := httptest.NewRecorder()
+		pc.Delete(w, newRequest(http.MethodDelete, url, nil))
+
+		assert.Equal(http.StatusNoContent, w.Code)
+		assert.Empty(w.Body.String())
+	})
+
+	t.Run("Testing not found", func(t *testing.T) {
+		srv.EXPECT().Delete(gomock.Any(), fmt.Sprint(current.Id)).Return(product.ErrNotFound)
+
+		w := httptest.NewRecorder()
+		pc.Delete(w, newRequest(http.MethodDelete, url, nil))
+
+		assert.Equal(http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Testing error", func(t *testing.T) {
+		srv.EXPECT().Delete(gomock.Any(), fmt.Sprint(current.Id)).Return(errors.New("boom"))
+
+		w := httptest.NewRecorder()
+		pc.Delete(w, newRequest(http.MethodDelete, url, nil))
+
+		assert.Equal(http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestApiHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	srv := mocks.NewMockProductModelService(ctrl)
+	pc := NewProductApiControl(srv, logging.Discard())
+
+	t.Run("Testing success result", func(t *testing.T) {
+		expected := []product.AuditEntry{{
+			Id:        1,
+			ProductId: 7,
+			Action:    product.ActionUpdate,
+			Actor:     "api_key:1a2b3c4d",
+			Before:    json.RawMessage(`{"quantity":1}`),
+			After:     json.RawMessage(`{"quantity":2}`),
+		}}
+		srv.EXPECT().History(gomock.Any(), "7").Return(expected, nil)
+
+		w := httptest.NewRecorder()
+		pc.History(w, newRequest(http.MethodGet, "/api/v1/products/7/history", nil))
+
+		res := []product.AuditEntry{}
+		decodeBody(t, w, &res)
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal(expected, res)
+	})
+
+	t.Run("Testing not found", func(t *testing.T) {
+		srv.EXPECT().History(gomock.Any(), "8").Return(nil, product.ErrNotFound)
+
+		w := httptest.NewRecorder()
+		pc.History(w, newRequest(http.MethodGet, "/api/v1/products/8/history", nil))
+
+		assert.Equal(http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Testing malformed id", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		pc.History(w, newRequest(http.MethodGet, "/api/v1/products/abc/history", nil))
+
+		assert.Equal(http.StatusNotFound, w.Code)
+	})
+}
+
+func TestApiWithMemoryStore(t *testing.T) {
+	assert := assert.New(t)
+	pc := NewProductApiControl(product.NewMemoryProductModelService(), logging.Discard())
+
+	w := httptest.NewRecorder()
+	pc.Create(w, newRequest(http.MethodPost, "/api/v1/products", strings.NewReader(`{"name":"pen","value":{"amount":"2.50","currency":"BRL"},"quantity":10}`)))
+	assert.Equal(http.StatusCreated, w.Code)
+	location := w.Header().Get("Location")
+
+	w = httptest.NewRecorder()
+	pc.Patch(w, newRequest(http.MethodPatch, location, strings.NewReader(`{"quantity":4}`)))
+	assert.Equal(http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	pc.Get(w, newRequest(http.MethodGet, location, nil))
+	res := product.Product{}
+	decodeBody(t, w, &res)
+	assert.Equal(product.Product{Id: 1, Name: "pen", Value: money.New(250, "BRL"), Quantity: 4}, res)
+
+	w = httptest.NewRecorder()
+	pc.Delete(w, newRequest(http.MethodDelete, location, nil))
+	assert.Equal(http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	pc.Get(w, newRequest(http.MethodGet, location, nil))
+	assert.Equal(http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	pc.History(w, newRequest(http.MethodGet, location+"/history", nil))
+	history := []product.AuditEntry{}
+	decodeBody(t, w, &history)
+	assert.Equal(http.StatusOK, w.Code, "the history outlives the product")
+	assert.Len(history, 3)
+	assert.Equal(`{"quantity":10}`, string(history[1].Before))
+}
+
+// failingWriter is a ResponseWriter whose client has gone away.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestWriteJSONLogsFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	srv := mocks.NewMockProductModelService(ctrl)
+	srv.EXPECT().Get(gomock.Any(), "1").Return(product.Product{Id: 1, Name: "pen"}, nil)
+
+	buf := &bytes.Buffer{}
+	logger, _ := logging.New(buf, "text", "info")
+
+	NewProductApiControl(srv, logger).Get(failingWriter{httptest.NewRecorder()}, newRequest(http.MethodGet, "/api/v1/products/1", nil))
+
+	assert.Contains(buf.String(), `level=WARN msg=http.write.failed error="broken pipe"`)
+}
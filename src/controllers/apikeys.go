@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/middleware"
+	"github.com/silastgoes/mock-store/src/model/apikey"
+)
+
+type apiKeyControl struct {
+	keys     apikey.APIKeyModelService
+	Template *template.Template
+	logger   *slog.Logger
+}
+
+//go:generate mockgen --source=apikeys.go --package=mocks --destination=./mocks/apikeys.go  APIKeyControlService
+type APIKeyControlService interface {
+	List(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+}
+
+func NewAPIKeyControl(path string, keys apikey.APIKeyModelService, logger *slog.Logger) *apiKeyControl {
+	return &apiKeyControl{
+		keys:     keys,
+		Template: template.Must(template.ParseGlob(path)),
+		logger:   logger,
+	}
+}
+
+// apiKeysData is what the APIKeys template renders. Token is the token of
+// the key just created, shown this once and never again.
+type apiKeysData struct {
+	Keys      []apikey.APIKey
+	Token     string
+	Name      string
+	Scope     string
+	Days      string
+	Error     string
+	CSRFToken string
+	Menu      menu
+}
+
+func (kc *apiKeyControl) List(w http.ResponseWriter, r *http.Request) {
+	kc.render(w, r, http.StatusOK, apiKeysData{Scope: string(apikey.Read)})
+}
+
+func (kc *apiKeyControl) Create(w http.ResponseWriter, r *http.Request) {
+	data := apiKeysData{
+		Name:  strings.TrimSpace(r.PostFormValue("name")),
+		Scope: r.PostFormValue("scope"),
+		Days:  strings.TrimSpace(r.PostFormValue("expires_days")),
+	}
+
+	expiresAt, err := expiry(data.Days)
+	if err != nil {
+		data.Error = err.Error()
+		kc.render(w, r, http.StatusUnprocessableEntity, data)
+		return
+	}
+
+	u, _ := middleware.CurrentUser(r.Context())
+	k, token, err := kc.keys.Create(r.Context(), data.Name, apikey.Scope(data.Scope), expiresAt, u.Id)
+	if errors.Is(err, apikey.ErrInvalid) {
+		kc.logger.WarnContext(r.Context(), logging.APIKeyFailed, "op", "create", "error", err)
+		data.Error = err.Error()
+		kc.render(w, r, http.StatusUnprocessableEntity, data)
+		return
+	}
+	if err != nil {
+		kc.logger.ErrorContext(r.Context(), logging.APIKeyFailed, "op", "create", "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	kc.logger.InfoContext(r.Context(), logging.APIKeyCreated, "id", k.Id, "prefix", k.Prefix, "scope", k.Scope)
+	kc.render(w, r, http.StatusCreated, apiKeysData{Token: token, Scope: string(apikey.Read)})
+}
+
+func (kc *apiKeyControl) Delete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	err := kc.keys.Delete(r.Context(), id)
+	switch {
+	case errors.Is(err, apikey.ErrNotFound), errors.Is(err, apikey.ErrInvalid):
+		http.Error(w, apikey.ErrNotFound.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		kc.logger.ErrorContext(r.Context(), logging.APIKeyFailed, "op", "delete", "id", id, "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	kc.logger.InfoContext(r.Context(), logging.APIKeyRevoked, "id", id)
+	http.Redirect(w, r, "/admin/api-keys", http.StatusSeeOther)
+}
+
+// render lists the keys under the form described by data.
+func (kc *apiKeyControl) render(w http.ResponseWriter, r *http.Request, status int, data apiKeysData) {
+	keys, err := kc.keys.List(r.Context())
+	if err != nil {
+		kc.logger.ErrorContext(r.Context(), logging.APIKeyFailed, "op", "list", "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	data.Keys = keys
+	data.CSRFToken = middleware.CSRFToken(r.Context())
+	data.Menu = newMenu(r, nil)
+
+	w.WriteHeader(status)
+	render(w, r, kc.Template, kc.logger, "APIKeys", data)
+}
+
+// expiry turns the "expires in days" field into a deadline; blank means the
+// key never expires.
+func expiry(days string) (*time.Time, error) {
+	if days == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(days)
+	if err != nil || n < 1 || n > 3650 {
+		return nil, errors.New("expiry must be a whole number of days from 1 to 3650")
+	}
+
+	t := time.Now().Add(time.Duration(n) * 24 * time.Hour)
+	return &t, nil
+}
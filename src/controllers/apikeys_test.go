@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/model/apikey"
+	keymocks "github.com/silastgoes/mock-store/src/model/apikey/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAPIKeyControl(ctrl *gomock.Controller) (*apiKeyControl, *keymocks.MockAPIKeyModelService) {
+	keys := keymocks.NewMockAPIKeyModelService(ctrl)
+	return NewAPIKeyControl(templatePath, keys, logging.Discard()), keys
+}
+
+func postAPIKey(form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/admin/api-keys", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestAPIKeyList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+	kc, keys := newAPIKeyControl(ctrl)
+	used := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	keys.EXPECT().List(gomock.Any()).Return([]apikey.APIKey{
+		{Id: 2, Name: "<b>reports</b>", Prefix: "ijklmnop", Scope: apikey.Read, CreatedAt: used, LastUsedAt: &used},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	kc.List(w, httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil))
+
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Contains(w.Body.String(), "&lt;b&gt;reports&lt;/b&gt;")
+	assert.Contains(w.Body.String(), "msk_ijklmnop")
+	assert.Contains(w.Body.String(), "2024-01-02 03:04")
+	assert.Contains(w.Body.String(), `action="/admin/api-keys/2"`)
+	assert.NotContains(w.Body.String(), `id="token"`)
+}
+
+func TestAPIKeyCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+	kc, keys := newAPIKeyControl(ctrl)
+
+	t.Run("Testing success", func(t *testing.T) {
+		keys.EXPECT().Create(gomock.Any(), "sync", apikey.Write, gomock.Not(gomock.Nil()), 0).
+			Return(apikey.APIKey{Id: 3, Prefix: "abcdefgh", Scope: apikey.Write}, "msk_secret", nil)
+		keys.EXPECT().List(gomock.Any()).Return([]apikey.APIKey{}, nil)
+
+		w := httptest.NewRecorder()
+		kc.Create(w, postAPIKey(url.Values{"name": {" sync "}, "scope": {"write"}, "expires_days": {"30"}}))
+
+		assert.Equal(http.StatusCreated, w.Code)
+		assert.Contains(w.Body.String(), `<code id="token">msk_secret</code>`)
+	})
+
+	t.Run("Testing invalid expiry", func(t *testing.T) {
+		keys.EXPECT().List(gomock.Any()).Return([]apikey.APIKey{}, nil)
+
+		w := httptest.NewRecorder()
+		kc.Create(w, postAPIKey(url.Values{"name": {"sync"}, "scope": {"write"}, "expires_days": {"0"}}))
+
+		assert.Equal(http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(w.Body.String(), "expiry must be a whole number of days")
+		assert.Contains(w.Body.String(), `value="sync"`)
+	})
+
+	t.Run("Testing invalid key", func(t *testing.T) {
+		keys.EXPECT().Create(gomock.Any(), "", apikey.Read, nil, 0).Return(apikey.APIKey{}, "", apikey.ErrInvalid)
+		keys.EXPECT().List(gomock.Any()).Return([]apikey.APIKey{}, nil)
+
+		w := httptest.NewRecorder()
+		kc.Create(w, postAPIKey(url.Values{"scope": {"read"}}))
+
+		assert.Equal(http.StatusUnprocessableEntity, w.Code)
+	})
+}
+
+func TestAPIKeyDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+	kc, keys := newAPIKeyControl(ctrl)
+
+	for _, tc := range []struct {
+		err    error
+		status int
+	}{
+		{nil, http.StatusSeeOther},
+		{apikey.ErrNotFound, http.StatusNotFound},
+		{errors.New("boom"), http.StatusInternalServerError},
+	} {
+		keys.EXPECT().Delete(gomock.Any(), "3").Return(tc.err)
+
+		req := httptest.NewRequest(http.MethodDelete, "/admin/api-keys/3", nil)
+		req.SetPathValue("id", "3")
+		w := httptest.NewRecorder()
+		kc.Delete(w, req)
+
+		assert.Equal(tc.status, w.Code)
+	}
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/middleware"
+	"github.com/silastgoes/mock-store/src/model/user"
+)
+
+type authControl struct {
+	users    user.UserModelService
+	sessions *middleware.Sessions
+	Template *template.Template
+	// SSO offers single sign-on on the login page; see oidcControl.
+	SSO    bool
+	logger *slog.Logger
+}
+
+//go:generate mockgen --source=auth.go --package=mocks --destination=./mocks/auth.go  AuthControlService
+type AuthControlService interface {
+	LoginPage(w http.ResponseWriter, r *http.Request)
+	Login(w http.ResponseWriter, r *http.Request)
+	Logout(w http.ResponseWriter, r *http.Request)
+}
+
+func NewAuthControl(path string, users user.UserModelService, sessions *middleware.Sessions, logger *slog.Logger) *authControl {
+	return &authControl{
+		users:    users,
+		sessions: sessions,
+		Template: template.Must(template.ParseGlob(path)),
+		logger:   logger,
+	}
+}
+
+// loginData is what the Login template renders. Next is where a successful
+// login goes.
+type loginData struct {
+	Username  string
+	Next      string
+	Error     string
+	SSO       bool
+	CSRFToken string
+	Menu      menu
+}
+
+func (ac *authControl) LoginPage(w http.ResponseWriter, r *http.Request) {
+	if _, ok := middleware.CurrentUser(r.Context()); ok {
+		http.Redirect(w, r, localPath(r.URL.Query().Get("next")), http.StatusSeeOther)
+		return
+	}
+
+	ac.render(w, r, http.StatusOK, loginData{Next: r.URL.Query().Get("next")})
+}
+
+func (ac *authControl) Login(w http.ResponseWriter, r *http.Request) {
+	data := loginData{
+		Username: strings.TrimSpace(r.PostFormValue("username")),
+		Next:     r.PostFormValue("next"),
+	}
+
+	u, err := ac.users.Authenticate(r.Context(), data.Username, r.PostFormValue("password"))
+	if errors.Is(err, user.ErrCredentials) {
+		ac.logger.WarnContext(r.Context(), logging.LoginFailed, "username", data.Username)
+		data.Error = "Wrong username or password."
+		ac.render(w, r, http.StatusUnauthorized, data)
+		return
+	}
+	if err == nil {
+		err = ac.sessions.Start(w, r, u)
+	}
+	if err != nil {
+		ac.logger.ErrorContext(r.Context(), logging.LoginFailed, "username", data.Username, "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	ac.logger.InfoContext(r.Context(), logging.LoginSucceeded, "username", u.Username)
+	http.Redirect(w, r, localPath(data.Next), http.StatusSeeOther)
+}
+
+func (ac *authControl) Logout(w http.ResponseWriter, r *http.Request) {
+	if err := ac.sessions.End(w, r); err != nil {
+		ac.logger.ErrorContext(r.Context(), logging.SessionFailed, "error", err)
+	} else {
+		ac.logger.InfoContext(r.Context(), logging.Logout)
+	}
+
+	http.Redirect(w, r, middleware.LoginPath, http.StatusSeeOther)
+}
+
+func (ac *authControl) render(w http.ResponseWriter, r *http.Request, status int, data loginData) {
+	data.SSO = ac.SSO
+	renderLogin(w, r, ac.Template, ac.logger, status, data)
+}
+
+func renderLogin(w http.ResponseWriter, r *http.Request, t *template.Template, logger *slog.Logger, status int, data loginData) {
+	data.CSRFToken = middleware.CSRFToken(r.Context())
+	data.Menu = newMenu(r, nil)
+
+	w.WriteHeader(status)
+	render(w, r, t, logger, "Login", data)
+}
+
+// localPath returns next when it is a path on this site and "/" otherwise,
+// so a crafted login link cannot send the user elsewhere afterwards.
+func localPath(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+
+	return next
+}
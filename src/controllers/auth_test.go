@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/middleware"
+	"github.com/silastgoes/mock-store/src/model/user"
+	usermocks "github.com/silastgoes/mock-store/src/model/user/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuthControl(ctrl *gomock.Controller) (*authControl, *usermocks.MockUserModelService) {
+	users := usermocks.NewMockUserModelService(ctrl)
+	sessions := middleware.NewSessions(users, []byte(strings.Repeat("k", 32)), time.Hour, logging.Discard())
+	return NewAuthControl(templatePath, users, sessions, logging.Discard()), users
+}
+
+func postLogin(form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestLoginPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+	ac, _ := newAuthControl(ctrl)
+
+	w := httptest.NewRecorder()
+	ac.LoginPage(w, httptest.NewRequest(http.MethodGet, "/login?next=%2Fproducts%2Fnew", nil))
+
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Contains(w.Body.String(), `name="next" value="/products/new"`)
+	assert.Contains(w.Body.String(), `name="password"`)
+	assert.NotContains(w.Body.String(), "Log out")
+}
+
+func TestLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+	ac, users := newAuthControl(ctrl)
+	ana := user.User{Id: 3, Username: "ana"}
+
+	t.Run("Testing success", func(t *testing.T) {
+		users.EXPECT().Authenticate(gomock.Any(), "ana", "correct horse").Return(ana, nil)
+		users.EXPECT().CreateSession(gomock.Any(), 3, time.Hour).Return("token", nil)
+
+		w := httptest.NewRecorder()
+		ac.Login(w, postLogin(url.Values{"username": {" ana "}, "password": {"correct horse"}, "next": {"/products/new"}}))
+
+		assert.Equal(http.StatusSeeOther, w.Code)
+		assert.Equal("/products/new", w.Header().Get("Location"))
+		assert.Equal(middleware.SessionCookie, w.Result().Cookies()[0].Name)
+	})
+
+	t.Run("Testing offsite next", func(t *testing.T) {
+		for _, next := range []string{"https://evil.example", "//evil.example", "/\\evil.example", ""} {
+			users.EXPECT().Authenticate(gomock.Any(), "ana", "correct horse").Return(ana, nil)
+			users.EXPECT().CreateSession(gomock.Any(), 3, time.Hour).Return("token", nil)
+
+			w := httptest.NewRecorder()
+			ac.Login(w, postLogin(url.Values{"username": {"ana"}, "password": {"correct horse"}, "next": {next}}))
+
+			assert.Equal("/", w.Header().Get("Location"), next)
+		}
+	})
+
+	t.Run("Testing wrong password", func(t *testing.T) {
+		users.EXPECT().Authenticate(gomock.Any(), "<b>ana</b>", "wrong").Return(user.User{}, user.ErrCredentials)
+
+		w := httptest.NewRecorder()
+		ac.Login(w, postLogin(url.Values{"username": {"<b>ana</b>"}, "password": {"wrong"}}))
+
+		assert.Equal(http.StatusUnauthorized, w.Code)
+		assert.Contains(w.Body.String(), "Wrong username or password.")
+		assert.Contains(w.Body.String(), `value="&lt;b&gt;ana&lt;/b&gt;"`)
+		assert.Empty(w.Result().Cookies())
+	})
+
+	t.Run("Testing store failure", func(t *testing.T) {
+		users.EXPECT().Authenticate(gomock.Any(), "ana", "correct horse").Return(user.User{}, errors.New("boom"))
+
+		w := httptest.NewRecorder()
+		ac.Login(w, postLogin(url.Values{"username": {"ana"}, "password": {"correct horse"}}))
+
+		assert.Equal(http.StatusInternalServerError, w.Code)
+		assert.Empty(w.Result().Cookies())
+	})
+}
+
+func TestLogout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+	ac, users := newAuthControl(ctrl)
+
+	users.EXPECT().CreateSession(gomock.Any(), 3, time.Hour).Return("token", nil)
+	login := httptest.NewRecorder()
+	assert.Nil(ac.sessions.Start(login, postLogin(nil), user.User{Id: 3}))
+
+	users.EXPECT().DeleteSession(gomock.Any(), "token").Return(nil)
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(login.Result().Cookies()[0])
+	w := httptest.NewRecorder()
+	ac.Logout(w, req)
+
+	assert.Equal(http.StatusSeeOther, w.Code)
+	assert.Equal("/login", w.Header().Get("Location"))
+	assert.Equal(-1, w.Result().Cookies()[0].MaxAge)
+}
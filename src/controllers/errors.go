@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/silastgoes/mock-store/src/model/product"
+)
+
+var statusByError = []struct {
+	err    error
+	status int
+}{
+	{product.ErrNotFound, http.StatusNotFound},
+	{product.ErrConflict, http.StatusConflict},
+	{product.ErrInvalid, http.StatusUnprocessableEntity},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout},
+}
+
+// statusFromError maps the model's domain errors onto HTTP statuses, along
+// with a message safe to show to clients. Anything else is a 500.
+func statusFromError(err error) (int, string) {
+	for _, s := range statusByError {
+		if errors.Is(err, s.err) {
+			return s.status, s.err.Error()
+		}
+	}
+
+	return http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+}
+
+// logFailure logs a failed service call under event: as a warning when the
+// client caused it, as an error otherwise.
+func logFailure(ctx context.Context, logger *slog.Logger, event string, err error, attrs ...any) {
+	level := slog.LevelError
+	if status, _ := statusFromError(err); status < http.StatusInternalServerError {
+		level = slog.LevelWarn
+	}
+
+	logger.Log(ctx, level, event, append(attrs, "error", err)...)
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/silastgoes/mock-store/src/middleware"
+	"github.com/silastgoes/mock-store/src/model/product"
+	"github.com/silastgoes/mock-store/src/money"
+)
+
+// productForm carries the raw values of the NewProduct and Edit forms, so a
+// rejected submission can be shown again exactly as it was typed, along
+// with what is wrong with each field.
+type productForm struct {
+	Id          string
+	Name        string
+	Description string
+	Value       string
+	Currency    string
+	Quantity    string
+	Errors      product.ValidationErrors
+	// CSRFToken is echoed back in a hidden field; see middleware.CSRF.
+	CSRFToken string
+	Menu      menu
+}
+
+func newProductForm(p product.Product) productForm {
+	return productForm{
+		Id:          strconv.Itoa(p.Id),
+		Name:        p.Name,
+		Description: p.Description,
+		Value:       p.Value.Decimal(),
+		Currency:    p.Value.Currency,
+		Quantity:    strconv.Itoa(p.Quantity),
+	}
+}
+
+// readProductForm reads a submitted form; the id, if any, comes from the
+// /products/{id} path.
+func readProductForm(r *http.Request) productForm {
+	return productForm{
+		Id:          r.PathValue("id"),
+		Name:        r.FormValue("name"),
+		Description: r.FormValue("description"),
+		Value:       strings.TrimSpace(r.FormValue("value")),
+		Currency:    r.FormValue("currency"),
+		Quantity:    strings.TrimSpace(r.FormValue("quantity")),
+		CSRFToken:   middleware.CSRFToken(r.Context()),
+		Menu:        newMenu(r, nil),
+	}
+}
+
+// fillDetails takes every field but the quantity from p when the form left
+// it blank, as the Edit page does for users who may only change stock.
+func (f *productForm) fillDetails(p product.Product) {
+	if f.Name == "" {
+		f.Name = p.Name
+	}
+	if f.Description == "" {
+		f.Description = p.Description
+	}
+	if f.Value == "" {
+		f.Value = p.Value.Decimal()
+	}
+	if f.Currency == "" {
+		f.Currency = p.Value.Currency
+	}
+}
+
+// product converts the form into a product, reporting unparseable numbers
+// and every broken validation rule together.
+func (f productForm) product() (product.Product, product.ValidationErrors) {
+	p := product.Product{
+		Name:        f.Name,
+		Description: f.Description,
+	}
+	errs := product.ValidationErrors{}
+
+	value, err := money.Parse(f.Value, f.Currency)
+	priceError(errs, err, f.Currency)
+	if err != nil {
+		value = money.New(0, money.DefaultCurrency)
+	}
+	p.Value = value
+
+	quantity, err := strconv.Atoi(f.Quantity)
+	if err != nil {
+		errs["quantity"] = "must be a whole number"
+	}
+	p.Quantity = quantity
+
+	if verr, ok := p.Validate().(product.ValidationErrors); ok {
+		for field, msg := range verr {
+			if _, seen := errs[field]; !seen {
+				errs[field] = msg
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return p, nil
+	}
+
+	return p, errs
+}
+
+// priceError records why money.Parse rejected a price in currency, if it
+// did, under the field the client has to fix.
+func priceError(errs product.ValidationErrors, err error, currency string) {
+	switch {
+	case errors.Is(err, money.ErrCurrency):
+		errs["currency"] = "is not supported"
+	case errors.Is(err, money.ErrPrecision):
+		errs["value"] = fmt.Sprintf("must have at most %d decimal places", money.Digits(currency))
+	case err != nil:
+		errs["value"] = "must be a number"
+	}
+}
+
+// Currencies are the choices offered by the form's currency select.
+func (f productForm) Currencies() []string {
+	return money.Currencies()
+}
+
+// Selected reports whether code is the form's currency, the default one
+// when the form has none yet.
+func (f productForm) Selected(code string) bool {
+	if f.Currency == "" {
+		return code == money.DefaultCurrency
+	}
+
+	return code == f.Currency
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/dbconnection"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/migrations"
+)
+
+// readyTimeout bounds each readiness check so a hung database cannot hold
+// the probe past the orchestrator's own deadline.
+var readyTimeout = 2 * time.Second
+
+// TemplateChecker reports whether the pages a controller renders are loaded.
+type TemplateChecker interface {
+	CheckTemplates() error
+}
+
+type healthControl struct {
+	version    string
+	pages      TemplateChecker
+	db         dbconnection.DbConnectionService
+	migrations migrations.MigrationService
+	logger     *slog.Logger
+	started    time.Time
+	now        func() time.Time
+}
+
+//go:generate mockgen --source=health.go --package=mocks --destination=./mocks/health.go  HealthControlService
+type HealthControlService interface {
+	Healthz(w http.ResponseWriter, r *http.Request)
+	Readyz(w http.ResponseWriter, r *http.Request)
+	Status(w http.ResponseWriter, r *http.Request)
+}
+
+// NewHealthControl builds the probes. db and mig are nil when the store
+// does not use a database, and their checks are then skipped.
+func NewHealthControl(version string, pages TemplateChecker, db dbconnection.DbConnectionService, mig migrations.MigrationService, logger *slog.Logger) *healthControl {
+	return &healthControl{
+		version:    version,
+		pages:      pages,
+		db:         db,
+		migrations: mig,
+		logger:     logger,
+		started:    time.Now(),
+		now:        time.Now,
+	}
+}
+
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+type poolStats struct {
+	MaxOpen        int   `json:"max_open"`
+	Open           int   `json:"open"`
+	InUse          int   `json:"in_use"`
+	Idle           int   `json:"idle"`
+	WaitCount      int64 `json:"wait_count"`
+	WaitDurationMs int64 `json:"wait_duration_ms"`
+}
+
+type status struct {
+	Version       string     `json:"version"`
+	StartedAt     time.Time  `json:"started_at"`
+	Uptime        string     `json:"uptime"`
+	UptimeSeconds int64      `json:"uptime_seconds"`
+	Database      *poolStats `json:"database,omitempty"`
+}
+
+// Healthz answers as long as the process can serve HTTP at all.
+func (hc *healthControl) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, hc.logger, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz answers 200 only when the store can serve requests: the database
+// answers, its schema is at the latest migration and the pages are loaded.
+// Otherwise it answers 503 naming every check that failed; why it failed
+// goes to the log only, as the endpoint is not authenticated.
+func (hc *healthControl) Readyz(w http.ResponseWriter, r *http.Request) {
+	res := readiness{Status: "ready", Checks: map[string]string{}}
+
+	for _, check := range []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		{"database", hc.checkDatabase},
+		{"migrations", hc.checkMigrations},
+		{"templates", func(context.Context) error { return hc.pages.CheckTemplates() }},
+	} {
+		ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+		err := check.run(ctx)
+		cancel()
+
+		switch {
+		case errors.Is(err, errSkipped):
+			res.Checks[check.name] = "skipped"
+		case err != nil:
+			res.Status = "unavailable"
+			res.Checks[check.name] = "failed"
+			hc.logger.WarnContext(r.Context(), logging.HealthCheckFailed, "check", check.name, "error", err)
+		default:
+			res.Checks[check.name] = "ok"
+		}
+	}
+
+	code := http.StatusOK
+	if res.Status != "ready" {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, r, hc.logger, code, res)
+}
+
+// Status describes the running build: its version, how long it has been up
+// and how its connection pool is being used.
+func (hc *healthControl) Status(w http.ResponseWriter, r *http.Request) {
+	uptime := hc.now().Sub(hc.started)
+	res := status{
+		Version:       hc.version,
+		StartedAt:     hc.started.UTC(),
+		Uptime:        uptime.Round(time.Second).String(),
+		UptimeSeconds: int64(uptime / time.Second),
+	}
+
+	if hc.db != nil {
+		stats := hc.db.Stats()
+		res.Database = &poolStats{
+			MaxOpen:        stats.MaxOpenConnections,
+			Open:           stats.OpenConnections,
+			InUse:          stats.InUse,
+			Idle:           stats.Idle,
+			WaitCount:      stats.WaitCount,
+			WaitDurationMs: stats.WaitDuration.Milliseconds(),
+		}
+	}
+
+	writeJSON(w, r, hc.logger, http.StatusOK, res)
+}
+
+var errSkipped = errors.New("skipped")
+
+func (hc *healthControl) checkDatabase(ctx context.Context) error {
+	if hc.db == nil {
+		return errSkipped
+	}
+
+	return hc.db.Ping(ctx)
+}
+
+func (hc *healthControl) checkMigrations(ctx context.Context) error {
+	if hc.migrations == nil {
+		return errSkipped
+	}
+
+	version, err := hc.migrations.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if latest := hc.migrations.Latest(); version != latest {
+		return fmt.Errorf("schema at version %d, want %d", version, latest)
+	}
+
+	return nil
+}
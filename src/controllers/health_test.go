@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	dbmocks "github.com/silastgoes/mock-store/src/dbconnection/mocks"
+	"github.com/silastgoes/mock-store/src/logging"
+	migmocks "github.com/silastgoes/mock-store/src/migrations/mocks"
+	"github.com/silastgoes/mock-store/src/model/product/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthz(t *testing.T) {
+	w := httptest.NewRecorder()
+	NewHealthControl("dev", nil, nil, nil, logging.Discard()).Healthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestReadyz(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	pages := NewProductControl(templatePath, mocks.NewMockProductModelService(ctrl), logging.Discard())
+
+	t.Run("Testing ready", func(t *testing.T) {
+		db := dbmocks.NewMockDbConnectionService(ctrl)
+		mig := migmocks.NewMockMigrationService(ctrl)
+		db.EXPECT().Ping(gomock.Any()).Return(nil)
+		mig.EXPECT().Version(gomock.Any()).Return(4, nil)
+		mig.EXPECT().Latest().Return(4)
+
+		w := httptest.NewRecorder()
+		NewHealthControl("dev", pages, db, mig, logging.Discard()).Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		assert.Equal(http.StatusOK, w.Code)
+		assert.JSONEq(`{"status":"ready","checks":{"database":"ok","migrations":"ok","templates":"ok"}}`, w.Body.String())
+	})
+
+	t.Run("Testing every failure is reported", func(t *testing.T) {
+		db := dbmocks.NewMockDbConnectionService(ctrl)
+		mig := migmocks.NewMockMigrationService(ctrl)
+		db.EXPECT().Ping(gomock.Any()).Return(errors.New("connection refused"))
+		mig.EXPECT().Version(gomock.Any()).Return(3, nil)
+		mig.EXPECT().Latest().Return(4)
+
+		broken := &productControl{Template: template.Must(template.New("Index").Parse(""))}
+		buf := &bytes.Buffer{}
+		logger, _ := logging.New(buf, "text", "info")
+
+		w := httptest.NewRecorder()
+		NewHealthControl("dev", broken, db, mig, logger).Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		assert.Equal(http.StatusServiceUnavailable, w.Code)
+		assert.JSONEq(`{"status":"unavailable","checks":{
+			"database":"failed",
+			"migrations":"failed",
+			"templates":"failed"}}`, w.Body.String())
+		assert.Contains(buf.String(), `level=WARN msg=health.check.failed check=database error="connection refused"`)
+		assert.Contains(buf.String(), `level=WARN msg=health.check.failed check=migrations error="schema at version 3, want 4"`)
+	})
+}
+
+func TestStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	db := dbmocks.NewMockDbConnectionService(ctrl)
+	db.EXPECT().Stats().Return(sql.DBStats{MaxOpenConnections: 10, OpenConnections: 3, InUse: 1, Idle: 2, WaitCount: 4, WaitDuration: 1500 * time.Millisecond})
+
+	hc := NewHealthControl("v1.2.3", nil, db, nil, logging.Discard())
+	hc.started = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	hc.now = func() time.Time { return hc.started.Add(90*time.Minute + 400*time.Millisecond) }
+
+	w := httptest.NewRecorder()
+	hc.Status(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	assert.Equal(http.StatusOK, w.Code)
+	assert.JSONEq(`{
+		"version": "v1.2.3",
+		"started_at": "2024-05-01T12:00:00Z",
+		"uptime": "1h30m0s",
+		"uptime_seconds": 5400,
+		"database": {"max_open": 10, "open": 3, "in_use": 1, "idle": 2, "wait_count": 4, "wait_duration_ms": 1500}
+	}`, w.Body.String())
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/silastgoes/mock-store/src/model/product"
+	"github.com/silastgoes/mock-store/src/money"
+)
+
+// listOptions reads the listing query parameters shared by the HTML index
+// and the JSON API: q, limit, offset, after, sort, order, currency,
+// min_value, max_value and in_stock. Price filters are read in currency,
+// or in money.DefaultCurrency when it is not given.
+func listOptions(q url.Values) (product.ListOptions, error) {
+	opts := product.ListOptions{
+		After:    q.Get("after"),
+		Query:    q.Get("q"),
+		Sort:     q.Get("sort"),
+		Currency: strings.ToUpper(q.Get("currency")),
+	}
+
+	var err error
+	if opts.Limit, err = intParam(q, "limit"); err != nil {
+		return opts, err
+	}
+	if opts.Offset, err = intParam(q, "offset"); err != nil {
+		return opts, err
+	}
+	currency := opts.Currency
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	if !money.Known(currency) {
+		return opts, fmt.Errorf("%w: currency must be one of %s", product.ErrInvalid, strings.Join(money.Currencies(), ", "))
+	}
+	if opts.MinValue, err = moneyParam(q, "min_value", currency); err != nil {
+		return opts, err
+	}
+	if opts.MaxValue, err = moneyParam(q, "max_value", currency); err != nil {
+		return opts, err
+	}
+
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		return opts, fmt.Errorf("%w: order must be asc or desc", product.ErrInvalid)
+	}
+
+	switch q.Get("in_stock") {
+	case "", "0", "false", "off":
+	default:
+		opts.InStock = true
+	}
+
+	return opts, nil
+}
+
+func intParam(q url.Values, name string) (int, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s must be an integer", product.ErrInvalid, name)
+	}
+
+	return v, nil
+}
+
+func moneyParam(q url.Values, name, currency string) (*money.Money, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	v, err := money.Parse(raw, currency)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be an amount with at most %d decimal places",
+			product.ErrInvalid, name, money.Digits(currency))
+	}
+
+	return &v, nil
+}
+
+// pageURL is the index URL for the same listing starting at offset.
+func pageURL(q url.Values, offset int) string {
+	next := url.Values{}
+	for k, v := range q {
+		next[k] = v
+	}
+
+	next.Del("after")
+	next.Set("offset", strconv.Itoa(offset))
+
+	return "/?" + next.Encode()
+}
+
+// cursorURL is the index URL for the same listing resuming after cursor.
+func cursorURL(q url.Values, cursor string) string {
+	next := url.Values{}
+	for k, v := range q {
+		next[k] = v
+	}
+
+	next.Del("offset")
+	next.Set("after", cursor)
+
+	return "/?" + next.Encode()
+}
@@ -58,6 +58,18 @@ func (mr *MockProductControlServiceMockRecorder) Edit(w, r interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Edit", reflect.TypeOf((*MockProductControlService)(nil).Edit), w, r)
 }
 
+// History mocks base method.
+func (m *MockProductControlService) History(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "History", w, r)
+}
+
+// History indicates an expected call of History.
+func (mr *MockProductControlServiceMockRecorder) History(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockProductControlService)(nil).History), w, r)
+}
+
 // Index mocks base method.
 func (m *MockProductControlService) Index(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/middleware"
+	"github.com/silastgoes/mock-store/src/model/user"
+	"github.com/silastgoes/mock-store/src/oidc"
+)
+
+// FlowCookie carries an OIDC login between Start and Callback.
+const FlowCookie = "oidc_flow"
+
+// flowPath scopes FlowCookie to the two OIDC routes.
+const flowPath = "/login/oidc"
+
+// flowTTL is how long a user has to sign in at the provider.
+const flowTTL = 10 * time.Minute
+
+type oidcControl struct {
+	provider *oidc.Provider
+	users    user.UserModelService
+	sessions *middleware.Sessions
+	Template *template.Template
+	logger   *slog.Logger
+}
+
+//go:generate mockgen --source=oidc.go --package=mocks --destination=./mocks/oidc.go  OIDCControlService
+type OIDCControlService interface {
+	Start(w http.ResponseWriter, r *http.Request)
+	Callback(w http.ResponseWriter, r *http.Request)
+}
+
+func NewOIDCControl(path string, provider *oidc.Provider, users user.UserModelService, sessions *middleware.Sessions, logger *slog.Logger) *oidcControl {
+	return &oidcControl{
+		provider: provider,
+		users:    users,
+		sessions: sessions,
+		Template: template.Must(template.ParseGlob(path)),
+		logger:   logger,
+	}
+}
+
+// Start sends the browser to the provider, remembering the login in a
+// signed cookie for Callback.
+func (oc *oidcControl) Start(w http.ResponseWriter, r *http.Request) {
+	f := oidc.NewFlow(localPath(r.URL.Query().Get("next")))
+
+	raw, err := json.Marshal(f)
+	if err != nil {
+		oc.logger.ErrorContext(r.Context(), logging.OIDCFailed, "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	oc.sessions.SetSigned(w, r, FlowCookie, flowPath, base64.RawURLEncoding.EncodeToString(raw), flowTTL)
+	http.Redirect(w, r, oc.provider.AuthCodeURL(f), http.StatusSeeOther)
+}
+
+// Callback finishes the login the provider sent the browser back from: it
+// redeems the code, maps the user's claims to a role and signs them in.
+func (oc *oidcControl) Callback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f, ok := oc.flow(r)
+	oc.sessions.ClearSigned(w, r, FlowCookie, flowPath)
+	if !ok || q.Get("state") != f.State {
+		oc.logger.WarnContext(r.Context(), logging.OIDCFailed, "reason", "state")
+		oc.render(w, r, http.StatusBadRequest, "The sign-in expired or was started elsewhere. Please try again.")
+		return
+	}
+
+	if e := q.Get("error"); e != "" {
+		oc.logger.WarnContext(r.Context(), logging.OIDCFailed, "reason", "provider", "error", e, "description", q.Get("error_description"))
+		oc.render(w, r, http.StatusUnauthorized, "Single sign-on was refused.")
+		return
+	}
+
+	claims, err := oc.provider.Exchange(r.Context(), f, q.Get("code"))
+	if err != nil {
+		oc.logger.ErrorContext(r.Context(), logging.OIDCFailed, "reason", "exchange", "error", err)
+		oc.render(w, r, http.StatusBadGateway, "Single sign-on failed. Please try again.")
+		return
+	}
+
+	role, ok := oc.provider.Role(claims)
+	if !ok {
+		oc.logger.WarnContext(r.Context(), logging.OIDCFailed, "reason", "role", "subject", claims.Subject)
+		oc.render(w, r, http.StatusForbidden, "Your account has no role in the store.")
+		return
+	}
+
+	username := oidc.Username(claims)
+	u, err := oc.users.Federate(r.Context(), claims.Issuer, claims.Subject, username, role)
+	switch {
+	case errors.Is(err, user.ErrConflict):
+		oc.logger.WarnContext(r.Context(), logging.OIDCFailed, "reason", "conflict", "username", username)
+		oc.render(w, r, http.StatusConflict, "The username "+username+" belongs to a password account.")
+		return
+	case errors.Is(err, user.ErrInvalid):
+		oc.logger.WarnContext(r.Context(), logging.OIDCFailed, "reason", "username", "username", username, "error", err)
+		oc.render(w, r, http.StatusForbidden, "Your account has no usable username.")
+		return
+	}
+	if err == nil {
+		err = oc.sessions.Start(w, r, u)
+	}
+	if err != nil {
+		oc.logger.ErrorContext(r.Context(), logging.OIDCFailed, "reason", "session", "username", username, "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	oc.logger.InfoContext(r.Context(), logging.LoginSucceeded, "username", u.Username, "method", "oidc", "role", u.Role)
+	http.Redirect(w, r, localPath(f.Next), http.StatusSeeOther)
+}
+
+// flow returns the login Start remembered for this browser.
+func (oc *oidcControl) flow(r *http.Request) (oidc.Flow, bool) {
+	f := oidc.Flow{}
+
+	value, ok := oc.sessions.Signed(r, FlowCookie)
+	if !ok {
+		return f, false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || json.Unmarshal(raw, &f) != nil || f.State == "" {
+		return f, false
+	}
+
+	return f, true
+}
+
+func (oc *oidcControl) render(w http.ResponseWriter, r *http.Request, status int, message string) {
+	renderLogin(w, r, oc.Template, oc.logger, status, loginData{Error: message, SSO: true})
+}
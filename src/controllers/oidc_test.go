@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/config"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/middleware"
+	"github.com/silastgoes/mock-store/src/model/user"
+	"github.com/silastgoes/mock-store/src/oidc"
+	"github.com/silastgoes/mock-store/src/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+)
+
+const callbackURL = "https://store.example.com/login/oidc/callback"
+
+// ssoFixture is an oidcControl signing users in through a fake provider
+// into a memory user store.
+type ssoFixture struct {
+	fake  *oidctest.Provider
+	oc    *oidcControl
+	users user.UserModelService
+}
+
+func newSSOFixture(t *testing.T) ssoFixture {
+	t.Helper()
+
+	fake := oidctest.NewProvider("mock-store", "secret")
+	t.Cleanup(fake.Close)
+
+	provider, err := oidc.Discover(context.Background(), config.OIDC{
+		Issuer:       fake.URL,
+		ClientID:     "mock-store",
+		ClientSecret: "secret",
+		RedirectURL:  callbackURL,
+		RoleClaim:    "groups",
+		Roles:        map[string]string{"store-managers": "manager"},
+	}, fake.Client())
+	assert.Nil(t, err)
+
+	users := user.NewMemoryUserModelService()
+	sessions := middleware.NewSessions(users, []byte(strings.Repeat("k", 32)), time.Hour, logging.Discard())
+
+	return ssoFixture{
+		fake:  fake,
+		oc:    NewOIDCControl(templatePath, provider, users, sessions, logging.Discard()),
+		users: users,
+	}
+}
+
+// login runs the browser's side of a sign-in starting at target and
+// returns the callback's response.
+func (f ssoFixture) login(t *testing.T, target string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	f.oc.Start(w, httptest.NewRequest(http.MethodGet, target, nil))
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	flow := w.Result().Cookies()[0]
+
+	client := f.fake.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	res, err := client.Get(w.Header().Get("Location"))
+	assert.Nil(t, err)
+	res.Body.Close()
+	back, err := url.Parse(res.Header.Get("Location"))
+	assert.Nil(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/login/oidc/callback?"+back.RawQuery, nil)
+	req.AddCookie(flow)
+	w = httptest.NewRecorder()
+	f.oc.Callback(w, req)
+
+	return w
+}
+
+func TestOIDCStart(t *testing.T) {
+	assert := assert.New(t)
+	f := newSSOFixture(t)
+
+	w := httptest.NewRecorder()
+	f.oc.Start(w, httptest.NewRequest(http.MethodGet, "/login/oidc?next=%2Fproducts%2Fnew", nil))
+
+	assert.Equal(http.StatusSeeOther, w.Code)
+	assert.True(strings.HasPrefix(w.Header().Get("Location"), f.fake.URL+"/authorize?"))
+	cookie := w.Result().Cookies()[0]
+	assert.Equal(FlowCookie, cookie.Name)
+	assert.Equal("/login/oidc", cookie.Path)
+	assert.True(cookie.HttpOnly)
+}
+
+func TestOIDCCallback(t *testing.T) {
+	assert := assert.New(t)
+	f := newSSOFixture(t)
+
+	t.Run("Testing success", func(t *testing.T) {
+		f.fake.SignIn(map[string]interface{}{"sub": "42", "email": "Ana@example.com", "groups": []string{"store-managers"}})
+
+		w := f.login(t, "/login/oidc?next=%2Fproducts%2Fnew")
+
+		assert.Equal(http.StatusSeeOther, w.Code)
+		assert.Equal("/products/new", w.Header().Get("Location"))
+		names := []string{}
+		for _, c := range w.Result().Cookies() {
+			names = append(names, c.Name)
+		}
+		assert.Equal([]string{FlowCookie, middleware.SessionCookie, middleware.CSRFCookie}, names)
+
+		u, err := f.users.Federate(context.Background(), f.fake.URL, "42", "ana", user.Manager)
+		assert.Nil(err)
+		assert.Equal(1, u.Id, "the second sign-in finds the same user")
+	})
+
+	t.Run("Testing offsite next", func(t *testing.T) {
+		w := f.login(t, "/login/oidc?next=%2F%2Fevil.example")
+		assert.Equal("/", w.Header().Get("Location"))
+	})
+
+	t.Run("Testing no role", func(t *testing.T) {
+		f.fake.SignIn(map[string]interface{}{"sub": "43", "preferred_username": "bob", "groups": "staff"})
+
+		w := f.login(t, "/login/oidc")
+
+		assert.Equal(http.StatusForbidden, w.Code)
+		assert.Contains(w.Body.String(), "Your account has no role in the store.")
+	})
+
+	t.Run("Testing username of a password user", func(t *testing.T) {
+		f.users.Create(context.Background(), "cris", "correct horse", user.Admin)
+		f.fake.SignIn(map[string]interface{}{"sub": "44", "preferred_username": "cris", "groups": "store-managers"})
+
+		w := f.login(t, "/login/oidc")
+
+		assert.Equal(http.StatusConflict, w.Code)
+	})
+
+	t.Run("Testing refused by the provider", func(t *testing.T) {
+		f.fake.SignIn(nil)
+
+		w := f.login(t, "/login/oidc")
+
+		assert.Equal(http.StatusUnauthorized, w.Code)
+		assert.Contains(w.Body.String(), "Single sign-on was refused.")
+	})
+
+	t.Run("Testing missing or mismatched state", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		f.oc.Callback(w, httptest.NewRequest(http.MethodGet, "/login/oidc/callback?code=c&state=s", nil))
+		assert.Equal(http.StatusBadRequest, w.Code)
+
+		w = httptest.NewRecorder()
+		f.oc.Start(w, httptest.NewRequest(http.MethodGet, "/login/oidc", nil))
+		req := httptest.NewRequest(http.MethodGet, "/login/oidc/callback?code=c&state=s", nil)
+		req.AddCookie(w.Result().Cookies()[0])
+		w = httptest.NewRecorder()
+		f.oc.Callback(w, req)
+		assert.Equal(http.StatusBadRequest, w.Code)
+		assert.Contains(w.Body.String(), "Please try again.")
+	})
+
+	t.Run("Testing bad code", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		f.oc.Start(w, httptest.NewRequest(http.MethodGet, "/login/oidc", nil))
+		flow := w.Result().Cookies()[0]
+		state, err := url.Parse(w.Header().Get("Location"))
+		assert.Nil(err)
+
+		req := httptest.NewRequest(http.MethodGet, "/login/oidc/callback?code=forged&state="+state.Query().Get("state"), nil)
+		req.AddCookie(flow)
+		w = httptest.NewRecorder()
+		f.oc.Callback(w, req)
+		assert.Equal(http.StatusBadGateway, w.Code)
+	})
+}
+
+func TestLoginPageOffersSSO(t *testing.T) {
+	assert := assert.New(t)
+	ac := NewAuthControl(templatePath, user.NewMemoryUserModelService(), nil, logging.Discard())
+
+	w := httptest.NewRecorder()
+	ac.LoginPage(w, httptest.NewRequest(http.MethodGet, "/login?next=%2Fproducts%2Fnew", nil))
+	assert.NotContains(w.Body.String(), "single sign-on")
+
+	ac.SSO = true
+	w = httptest.NewRecorder()
+	ac.LoginPage(w, httptest.NewRequest(http.MethodGet, "/login?next=%2Fproducts%2Fnew", nil))
+	assert.Contains(w.Body.String(), `href="/login/oidc?next=%2fproducts%2fnew"`)
+}
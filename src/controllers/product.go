@@ -1,17 +1,32 @@
 package controllers
 
 import (
-	"log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
-	"text/template"
 
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/middleware"
 	"github.com/silastgoes/mock-store/src/model/product"
+	"github.com/silastgoes/mock-store/src/model/user"
+	"github.com/silastgoes/mock-store/src/money"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 )
 
+// tracerName names the instrumentation scope of the spans started here.
+const tracerName = "github.com/silastgoes/mock-store/src/controllers"
+
 type productControl struct {
 	productService product.ProductModelService
 	Template       *template.Template
+	logger         *slog.Logger
 }
 
 //go:generate mockgen --source=product.go --package=mocks --destination=./mocks/product.go  ProductControlService
@@ -22,128 +37,348 @@ type ProductControlService interface {
 	Update(w http.ResponseWriter, r *http.Request)
 	Delete(w http.ResponseWriter, r *http.Request)
 	Edit(w http.ResponseWriter, r *http.Request)
+	History(w http.ResponseWriter, r *http.Request)
 }
 
-func NewProductControl(path string, svr product.ProductModelService) *productControl {
+func NewProductControl(path string, svr product.ProductModelService, logger *slog.Logger) *productControl {
 	temp := template.Must(template.ParseGlob(path))
 
 	return &productControl{
 		productService: svr,
 		Template:       temp,
+		logger:         logger,
+	}
+}
+
+// pageTemplates are the templates the pages below execute by name.
+var pageTemplates = []string{"Index", "NewProduct", "Edit", "Login", "APIKeys", "History"}
+
+// CheckTemplates reports a page template that failed to load.
+func (pc *productControl) CheckTemplates() error {
+	for _, name := range pageTemplates {
+		if pc.Template.Lookup(name) == nil {
+			return fmt.Errorf("template %q is not loaded", name)
+		}
+	}
+
+	return nil
+}
+
+// menu is what the _menu template renders: the search box and, once
+// signed in, the username and a button to sign out. Pages also ask it what
+// the user may do, to hide what they can't.
+type menu struct {
+	Query     url.Values
+	User      string
+	Role      user.Role
+	CSRFToken string
+}
+
+func newMenu(r *http.Request, query url.Values) menu {
+	m := menu{Query: query, CSRFToken: middleware.CSRFToken(r.Context())}
+	if u, ok := middleware.CurrentUser(r.Context()); ok {
+		m.User = u.Username
+		m.Role = u.Role
 	}
+
+	return m
+}
+
+// Can reports whether the signed-in user's role allows p.
+func (m menu) Can(p user.Permission) bool {
+	return m.Role.Can(p)
+}
+
+// indexData is what the Index template renders: one page of products, the
+// query that selected it and links to the neighbouring pages. From and To
+// are zero on pages reached by cursor.
+type indexData struct {
+	product.Page
+	Query     url.Values
+	From, To  int
+	CSRFToken string
+	Prev      string
+	Next      string
+	Menu      menu
+}
+
+// Currencies are the choices offered by the currency filter.
+func (d indexData) Currencies() []string {
+	return money.Currencies()
 }
 
 func (pc *productControl) Index(w http.ResponseWriter, r *http.Request) {
-	products, err := pc.productService.GetProducts()
+	query := r.URL.Query()
+	opts, err := listOptions(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
 
+	page, err := pc.productService.ListProducts(r.Context(), opts)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Println("Erro em recuperação de produtos:", err)
+		logFailure(r.Context(), pc.logger, logging.ProductListFailed, err)
+		pc.fail(w, err)
 		return
 	}
 
+	data := indexData{Page: page, Query: query, CSRFToken: middleware.CSRFToken(r.Context()), Menu: newMenu(r, query)}
+	if opts.After != "" {
+		// A keyset page does not know how far into the listing it is, so it
+		// shows no range and links back to the start instead of one page up.
+		data.Prev = pageURL(query, 0)
+		if page.NextCursor != "" {
+			data.Next = cursorURL(query, page.NextCursor)
+		}
+	} else {
+		if len(page.Products) > 0 {
+			data.From = page.Offset + 1
+			data.To = page.Offset + len(page.Products)
+		}
+		if page.Offset > 0 {
+			prev := page.Offset - page.Limit
+			if prev < 0 {
+				prev = 0
+			}
+			data.Prev = pageURL(query, prev)
+		}
+		if data.To < page.Total {
+			data.Next = pageURL(query, data.To)
+		}
+	}
+
 	w.WriteHeader(http.StatusOK)
-	pc.Template.ExecuteTemplate(w, "Index", products)
+	pc.render(w, r, "Index", data)
 }
 
 func (pc *productControl) New(w http.ResponseWriter, r *http.Request) {
-	pc.Template.ExecuteTemplate(w, "NewProduct", nil)
+	pc.render(w, r, "NewProduct", productForm{CSRFToken: middleware.CSRFToken(r.Context()), Menu: newMenu(r, nil)})
 }
 
 func (pc *productControl) Insert(w http.ResponseWriter, r *http.Request) {
-	status := http.StatusMovedPermanently
-	if r.Method == "POST" {
-		name := r.FormValue("name")
-		description := r.FormValue("description")
-		value := r.FormValue("value")
-		quantity := r.FormValue("quantity")
-
-		convertedValue, err := strconv.ParseFloat(value, 64)
-		if err != nil {
-			status = http.StatusBadRequest
-			log.Println("Erro na converção de preço:", err)
-		}
+	form := readProductForm(r)
 
-		convertedQuantity, err := strconv.Atoi(quantity)
-		if err != nil {
-			status = http.StatusBadRequest
-			log.Println("Erro na converção de quantidade:", err)
-		}
+	p, errs := form.product()
+	if errs != nil {
+		pc.logger.InfoContext(r.Context(), logging.ProductInvalid, "error", errs)
+		pc.invalid(w, r, "NewProduct", form, errs)
+		return
+	}
 
-		if status == http.StatusMovedPermanently {
-			err = pc.productService.Create(name, description, convertedValue, convertedQuantity)
-			if err != nil {
-				log.Println("Erro na criação de produto:", err)
-				status = http.StatusInternalServerError
-			}
-		}
+	_, err := pc.productService.Create(r.Context(), p.Name, p.Description, p.Value, p.Quantity)
+	if err != nil {
+		logFailure(r.Context(), pc.logger, logging.ProductCreateFailed, err)
+		pc.failForm(w, r, "NewProduct", form, err)
+		return
 	}
 
-	http.Redirect(w, r, "/", status)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
 func (pc *productControl) Delete(w http.ResponseWriter, r *http.Request) {
-	status := http.StatusMovedPermanently
-	id := r.URL.Query().Get("id")
+	id := r.PathValue("id")
 
-	err := pc.productService.Delete(id)
+	err := pc.productService.Delete(r.Context(), id)
 	if err != nil {
-		log.Println("Erro ao deletar um produto:", err)
-		status = http.StatusInternalServerError
+		logFailure(r.Context(), pc.logger, logging.ProductDeleteFailed, err, "product_id", id)
+		pc.fail(w, err)
+		return
 	}
 
-	http.Redirect(w, r, "/", status)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
 func (pc *productControl) Edit(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Query().Get("id")
-	status := http.StatusOK
+	id := r.PathValue("id")
 
-	p, err := pc.productService.Get(id)
+	p, err := pc.productService.Get(r.Context(), id)
 	if err != nil {
-		log.Println("Erro na busca de produtos:", err)
-		status = http.StatusInternalServerError
+		logFailure(r.Context(), pc.logger, logging.ProductGetFailed, err, "product_id", id)
+		pc.fail(w, err)
+		return
 	}
 
-	w.WriteHeader(status)
-	pc.Template.ExecuteTemplate(w, "Edit", p)
+	w.WriteHeader(http.StatusOK)
+	form := newProductForm(p)
+	form.CSRFToken = middleware.CSRFToken(r.Context())
+	form.Menu = newMenu(r, nil)
+	pc.render(w, r, "Edit", form)
 }
 
+// Update saves an edited product. A user allowed only EditQuantity may
+// leave the other fields out, and gets 403 for changing any of them; only
+// the quantity of what they send is written, so a concurrent edit of the
+// details is kept.
 func (pc *productControl) Update(w http.ResponseWriter, r *http.Request) {
-	status := http.StatusMovedPermanently
-	if r.Method == "POST" {
-		id := r.FormValue("id")
-		name := r.FormValue("name")
-		description := r.FormValue("description")
-		value := r.FormValue("value")
-		quantity := r.FormValue("quantity")
-
-		convertedValue, err := strconv.ParseFloat(value, 64)
-		if err != nil {
-			log.Println("Erro na converção de preço:", err)
-			status = http.StatusBadRequest
-		}
+	form := readProductForm(r)
 
-		convertedQuantity, err := strconv.Atoi(quantity)
+	convertedId, err := strconv.Atoi(form.Id)
+	if err != nil {
+		pc.logger.InfoContext(r.Context(), logging.ProductInvalid, "product_id", form.Id, "error", err)
+		pc.fail(w, product.ErrNotFound)
+		return
+	}
+
+	var current *product.Product
+	if u, ok := middleware.CurrentUser(r.Context()); ok && !u.Role.Can(user.EditProducts) {
+		p, err := pc.productService.Get(r.Context(), form.Id)
 		if err != nil {
-			log.Println("Erro na converção de quantidade:", err)
-			status = http.StatusBadRequest
+			logFailure(r.Context(), pc.logger, logging.ProductGetFailed, err, "product_id", form.Id)
+			pc.fail(w, err)
+			return
 		}
+		form.fillDetails(p)
+		current = &p
+	}
 
-		convertedId, err := strconv.Atoi(id)
-		if err != nil {
-			log.Println("Erro na converção de id:", err)
-			status = http.StatusNotFound
+	p, errs := form.product()
+	if errs != nil {
+		pc.logger.InfoContext(r.Context(), logging.ProductInvalid, "product_id", convertedId, "error", errs)
+		pc.invalid(w, r, "Edit", form, errs)
+		return
+	}
+
+	if current != nil && (p.Name != current.Name || p.Description != current.Description || p.Value != current.Value) {
+		middleware.Forbid(w, r, pc.logger, user.EditProducts)
+		return
+	}
+
+	if current != nil {
+		err = pc.productService.SetQuantity(r.Context(), convertedId, p.Quantity)
+	} else {
+		err = pc.productService.Update(r.Context(), convertedId, p.Name, p.Description, p.Value, p.Quantity)
+	}
+	if err != nil {
+		logFailure(r.Context(), pc.logger, logging.ProductUpdateFailed, err, "product_id", convertedId)
+		pc.failForm(w, r, "Edit", form, err)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// historyData is what the History template renders: the changes recorded
+// for one product, oldest first.
+type historyData struct {
+	Id      string
+	Entries []historyEntry
+	Menu    menu
+}
+
+// historyEntry is an audit entry with the fields it changed side by side.
+type historyEntry struct {
+	product.AuditEntry
+	Changes []fieldChange
+}
+
+type fieldChange struct {
+	Field, Before, After string
+}
+
+// History shows the audit log of a product, which outlives the product.
+func (pc *productControl) History(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	entries, err := pc.productService.History(r.Context(), id)
+	if err != nil {
+		logFailure(r.Context(), pc.logger, logging.ProductAuditFailed, err, "product_id", id)
+		pc.fail(w, err)
+		return
+	}
+
+	data := historyData{Id: id, Menu: newMenu(r, nil)}
+	for _, e := range entries {
+		data.Entries = append(data.Entries, historyEntry{AuditEntry: e, Changes: changes(e)})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	pc.render(w, r, "History", data)
+}
+
+// changes lines up the fields e changed, by name.
+func changes(e product.AuditEntry) []fieldChange {
+	before, after := map[string]json.RawMessage{}, map[string]json.RawMessage{}
+	json.Unmarshal(e.Before, &before)
+	json.Unmarshal(e.After, &after)
+
+	names := []string{}
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
 		}
+	}
+	sort.Strings(names)
 
-		if status == http.StatusMovedPermanently {
-			err = pc.productService.Update(convertedId, name, description, convertedValue, convertedQuantity)
-			if err != nil {
-				log.Println("Erro no update de produto:", err)
-				status = http.StatusInternalServerError
-			}
+	res := make([]fieldChange, len(names))
+	for i, name := range names {
+		res[i] = fieldChange{Field: name, Before: showField(name, before[name]), After: showField(name, after[name])}
+	}
+
+	return res
+}
+
+// showField renders a field of a product's JSON as the pages show it.
+func showField(name string, raw json.RawMessage) string {
+	if raw == nil {
+		return ""
+	}
+
+	if name == "value" {
+		var m money.Money
+		if json.Unmarshal(raw, &m) == nil {
+			return m.String()
 		}
 	}
 
-	http.Redirect(w, r, "/", status)
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+
+	return string(raw)
+}
+
+// invalid shows a rejected form again with its field errors.
+func (pc *productControl) invalid(w http.ResponseWriter, r *http.Request, name string, form productForm, errs product.ValidationErrors) {
+	form.Errors = errs
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	pc.render(w, r, name, form)
+}
+
+func (pc *productControl) render(w http.ResponseWriter, r *http.Request, name string, data interface{}) {
+	render(w, r, pc.Template, pc.logger, name, data)
+}
+
+// render executes the named page in a span of its own, so a slow template
+// stands apart from a slow query in the request's trace.
+func render(w http.ResponseWriter, r *http.Request, t *template.Template, logger *slog.Logger, name string, data interface{}) {
+	ctx, span := otel.Tracer(tracerName).Start(r.Context(), "template."+name)
+	defer span.End()
+
+	if err := t.ExecuteTemplate(w, name, data); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logger.WarnContext(ctx, logging.HttpWriteFailed, "template", name, "error", err)
+	}
+}
+
+// failForm is fail for form submissions: validation errors raised by the
+// service are shown on the form instead of an error page.
+func (pc *productControl) failForm(w http.ResponseWriter, r *http.Request, name string, form productForm, err error) {
+	var errs product.ValidationErrors
+	if errors.As(err, &errs) {
+		pc.invalid(w, r, name, form, errs)
+		return
+	}
+
+	pc.fail(w, err)
+}
+
+// fail answers a failed service call with the status matching its error.
+func (pc *productControl) fail(w http.ResponseWriter, err error) {
+	status, message := statusFromError(err)
+	http.Error(w, message, status)
 }
@@ -1,16 +1,25 @@
 package controllers
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/middleware"
 	"github.com/silastgoes/mock-store/src/model/product"
 	"github.com/silastgoes/mock-store/src/model/product/mocks"
+	"github.com/silastgoes/mock-store/src/model/user"
+	"github.com/silastgoes/mock-store/src/money"
 	"github.com/silastgoes/mock-store/src/util"
 	"github.com/stretchr/testify/assert"
 )
@@ -26,7 +35,7 @@ func RandonProduct() product.Product {
 		Name:        util.RandomString(6),
 		Description: util.RandomString(20),
 		Quantity:    util.RandomInt(1, 2000),
-		Value:       util.RandomFloat(),
+		Value:       util.RandomPrice(),
 	}
 }
 
@@ -38,10 +47,14 @@ func TestIndexSucess(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	srv := mocks.NewMockProductModelService(ctrl)
-	pc := NewProductControl(templatePath, srv)
-
-	srv.EXPECT().GetProducts().Return([]product.Product{
-		0: RandonProduct(),
+	pc := NewProductControl(templatePath, srv, logging.Discard())
+
+	srv.EXPECT().ListProducts(gomock.Any(), product.ListOptions{}).Return(product.Page{
+		Products: []product.Product{
+			0: RandonProduct(),
+		},
+		Total: 1,
+		Limit: product.DefaultPageSize,
 	}, nil)
 	pc.Index(w, req)
 	res := w.Result()
@@ -57,31 +70,102 @@ func TestIndexWithError(t *testing.T) {
 	assert := assert.New(t)
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
-	w := httptest.NewRecorder()
 
 	srv := mocks.NewMockProductModelService(ctrl)
-	pc := NewProductControl(templatePath, srv)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
+
+	for _, tc := range []struct {
+		err    error
+		status int
+	}{
+		{errors.New("boom"), http.StatusInternalServerError},
+		{fmt.Errorf("%w: bad cursor", product.ErrInvalid), http.StatusUnprocessableEntity},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+	} {
+		srv.EXPECT().ListProducts(gomock.Any(), gomock.Any()).Return(product.Page{}, tc.err)
 
-	errorExpected := errors.New("boom")
-	srv.EXPECT().GetProducts().Return([]product.Product{}, errorExpected)
-	pc.Index(w, req)
-	res := w.Result()
-	defer res.Body.Close()
-	_, err := ioutil.ReadAll(res.Body)
+		w := httptest.NewRecorder()
+		pc.Index(w, req)
 
-	assert.Nil(err)
-	assert.Equal(res.StatusCode, http.StatusBadRequest)
+		assert.Equal(tc.status, w.Code, tc.err.Error())
+		assert.NotEmpty(w.Body.String())
+	}
+}
+
+func TestIndexPagination(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := product.NewMemoryProductModelService()
+	pc := NewProductControl(templatePath, srv, logging.Discard())
+	for i := 0; i < 5; i++ {
+		p := RandonProduct()
+		srv.Create(context.Background(), p.Name, p.Description, p.Value, p.Quantity)
+	}
+
+	t.Run("Testing pager links", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/?limit=2&offset=2&sort=name", nil)
+		w := httptest.NewRecorder()
+
+		pc.Index(w, req)
+		body := w.Body.String()
+
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Contains(body, "3&ndash;4 of 5")
+		assert.Contains(body, `href="/?limit=2&amp;offset=0&amp;sort=name"`)
+		assert.Contains(body, `href="/?limit=2&amp;offset=4&amp;sort=name"`)
+	})
+
+	t.Run("Testing cursor pages", func(t *testing.T) {
+		first, _ := srv.ListProducts(context.Background(), product.ListOptions{Limit: 2, Sort: "name"})
+		second, _ := srv.ListProducts(context.Background(), product.ListOptions{Limit: 2, Sort: "name", After: first.NextCursor})
+		req := httptest.NewRequest(http.MethodGet, "/?limit=2&sort=name&after="+url.QueryEscape(first.NextCursor), nil)
+		w := httptest.NewRecorder()
+
+		pc.Index(w, req)
+		body := w.Body.String()
+
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Contains(body, "5 products")
+		assert.NotContains(body, "1&ndash;2 of 5")
+		assert.Contains(body, "<td>"+second.Products[0].Name+"</td>")
+		assert.Contains(body, `href="/?limit=2&amp;offset=0&amp;sort=name"`)
+		assert.Contains(body, `href="/?after=`+url.QueryEscape(second.NextCursor)+`&amp;limit=2&amp;sort=name"`)
+	})
+
+	t.Run("Testing search", func(t *testing.T) {
+		srv.Create(context.Background(), "Needle", "found it", money.New(123456, "BRL"), 1)
+		req := httptest.NewRequest(http.MethodGet, "/?q=needle", nil)
+		w := httptest.NewRecorder()
+
+		pc.Index(w, req)
+		body := w.Body.String()
+
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Contains(body, "1&ndash;1 of 1")
+		assert.Contains(body, "<td>Needle</td>")
+		assert.Contains(body, "<td>R$ 1.234,56</td>")
+		assert.Contains(body, `name="q" value="needle"`)
+	})
+
+	t.Run("Testing invalid parameters", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/?order=sideways", nil)
+		w := httptest.NewRecorder()
+
+		pc.Index(w, req)
+
+		assert.Equal(http.StatusUnprocessableEntity, w.Code)
+	})
 }
 
 func TestNewSucess(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	assert := assert.New(t)
 
-	req := httptest.NewRequest(http.MethodGet, "/new", nil)
+	req := newRequest(http.MethodGet, "/products/new", nil)
 	w := httptest.NewRecorder()
 
 	srv := mocks.NewMockProductModelService(ctrl)
-	pc := NewProductControl(templatePath, srv)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
 
 	pc.New(w, req)
 	res := w.Result()
@@ -97,11 +181,11 @@ func TestInsertSucess(t *testing.T) {
 	assert := assert.New(t)
 
 	product := RandonProduct()
-	req := httptest.NewRequest(http.MethodPost, "/insert", nil)
+	req := newRequest(http.MethodPost, "/products", nil)
 	form := map[string][]string{
 		"name":        {product.Name},
 		"description": {product.Description},
-		"value":       {fmt.Sprint(product.Value)},
+		"value":       {product.Value.Decimal()},
 		"quantity":    {fmt.Sprint(product.Quantity)},
 	}
 
@@ -109,13 +193,14 @@ func TestInsertSucess(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	srv := mocks.NewMockProductModelService(ctrl)
-	pc := NewProductControl(templatePath, srv)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
 	srv.EXPECT().Create(
+		gomock.Any(),
 		product.Name,
 		product.Description,
 		product.Value,
 		product.Quantity,
-	).Return(nil).AnyTimes()
+	).Return(product.Id, nil).AnyTimes()
 
 	pc.Insert(w, req)
 	res := w.Result()
@@ -123,7 +208,7 @@ func TestInsertSucess(t *testing.T) {
 	_, err := ioutil.ReadAll(res.Body)
 
 	assert.Nil(err)
-	assert.Equal(res.StatusCode, http.StatusMovedPermanently)
+	assert.Equal(res.StatusCode, http.StatusSeeOther)
 }
 
 func TestInsertBadRequestValue(t *testing.T) {
@@ -133,7 +218,7 @@ func TestInsertBadRequestValue(t *testing.T) {
 	product := RandonProduct()
 
 	t.Run("Bad Value in field: value", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodPost, "/insert", nil)
+		req := newRequest(http.MethodPost, "/products", nil)
 		form := map[string][]string{
 			"name":        {product.Name},
 			"description": {product.Description},
@@ -145,13 +230,14 @@ func TestInsertBadRequestValue(t *testing.T) {
 		w := httptest.NewRecorder()
 
 		srv := mocks.NewMockProductModelService(ctrl)
-		pc := NewProductControl(templatePath, srv)
+		pc := NewProductControl(templatePath, srv, logging.Discard())
 		srv.EXPECT().Create(
+			gomock.Any(),
 			product.Name,
 			product.Description,
 			gomock.Any(),
 			product.Quantity,
-		).Return(nil).AnyTimes()
+		).Return(0, nil).AnyTimes()
 
 		pc.Insert(w, req)
 		res := w.Result()
@@ -159,15 +245,15 @@ func TestInsertBadRequestValue(t *testing.T) {
 		_, err := ioutil.ReadAll(res.Body)
 
 		assert.Nil(err)
-		assert.Equal(res.StatusCode, http.StatusBadRequest)
+		assert.Equal(res.StatusCode, http.StatusUnprocessableEntity)
 	})
 
 	t.Run("Bad Value in field: value", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodPost, "/insert", nil)
+		req := newRequest(http.MethodPost, "/products", nil)
 		form := map[string][]string{
 			"name":        {product.Name},
 			"description": {product.Description},
-			"value":       {fmt.Sprint(product.Value)},
+			"value":       {product.Value.Decimal()},
 			"quantity":    {"quantity"},
 		}
 
@@ -175,13 +261,14 @@ func TestInsertBadRequestValue(t *testing.T) {
 		w := httptest.NewRecorder()
 
 		srv := mocks.NewMockProductModelService(ctrl)
-		pc := NewProductControl(templatePath, srv)
+		pc := NewProductControl(templatePath, srv, logging.Discard())
 		srv.EXPECT().Create(
+			gomock.Any(),
 			product.Name,
 			product.Description,
 			product.Value,
 			gomock.Any(),
-		).Return(nil).AnyTimes()
+		).Return(0, nil).AnyTimes()
 
 		pc.Insert(w, req)
 		res := w.Result()
@@ -189,7 +276,7 @@ func TestInsertBadRequestValue(t *testing.T) {
 		_, err := ioutil.ReadAll(res.Body)
 
 		assert.Nil(err)
-		assert.Equal(res.StatusCode, http.StatusBadRequest)
+		assert.Equal(res.StatusCode, http.StatusUnprocessableEntity)
 	})
 }
 
@@ -198,11 +285,11 @@ func TestInsertCreateError(t *testing.T) {
 	assert := assert.New(t)
 
 	product := RandonProduct()
-	req := httptest.NewRequest(http.MethodPost, "/insert", nil)
+	req := newRequest(http.MethodPost, "/products", nil)
 	form := map[string][]string{
 		"name":        {product.Name},
 		"description": {product.Description},
-		"value":       {fmt.Sprint(product.Value)},
+		"value":       {product.Value.Decimal()},
 		"quantity":    {fmt.Sprint(product.Quantity)},
 	}
 
@@ -210,15 +297,16 @@ func TestInsertCreateError(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	srv := mocks.NewMockProductModelService(ctrl)
-	pc := NewProductControl(templatePath, srv)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
 
 	errorExpected := errors.New("boom")
 	srv.EXPECT().Create(
+		gomock.Any(),
 		product.Name,
 		product.Description,
 		product.Value,
 		product.Quantity,
-	).Return(errorExpected).AnyTimes()
+	).Return(0, errorExpected).AnyTimes()
 
 	pc.Insert(w, req)
 	res := w.Result()
@@ -234,13 +322,13 @@ func TestDeleteSucess(t *testing.T) {
 	assert := assert.New(t)
 
 	product := RandonProduct()
-	req := httptest.NewRequest(http.MethodGet, "/delete?id="+fmt.Sprint(product.Id), nil)
+	req := newRequest(http.MethodDelete, "/products/"+fmt.Sprint(product.Id), nil)
 	w := httptest.NewRecorder()
 
 	srv := mocks.NewMockProductModelService(ctrl)
-	pc := NewProductControl(templatePath, srv)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
 
-	srv.EXPECT().Delete(fmt.Sprint(product.Id)).Return(nil).AnyTimes()
+	srv.EXPECT().Delete(gomock.Any(), fmt.Sprint(product.Id)).Return(nil).AnyTimes()
 
 	pc.Delete(w, req)
 	res := w.Result()
@@ -248,7 +336,7 @@ func TestDeleteSucess(t *testing.T) {
 	_, err := ioutil.ReadAll(res.Body)
 
 	assert.Nil(err)
-	assert.Equal(res.StatusCode, http.StatusMovedPermanently)
+	assert.Equal(res.StatusCode, http.StatusSeeOther)
 }
 
 func TestDeleteError(t *testing.T) {
@@ -256,14 +344,14 @@ func TestDeleteError(t *testing.T) {
 	assert := assert.New(t)
 
 	product := RandonProduct()
-	req := httptest.NewRequest(http.MethodGet, "/delete?id="+fmt.Sprint(product.Id), nil)
+	req := newRequest(http.MethodDelete, "/products/"+fmt.Sprint(product.Id), nil)
 	w := httptest.NewRecorder()
 
 	srv := mocks.NewMockProductModelService(ctrl)
-	pc := NewProductControl(templatePath, srv)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
 
 	errorExpected := errors.New("boom")
-	srv.EXPECT().Delete(fmt.Sprint(product.Id)).Return(errorExpected).AnyTimes()
+	srv.EXPECT().Delete(gomock.Any(), fmt.Sprint(product.Id)).Return(errorExpected).AnyTimes()
 
 	pc.Delete(w, req)
 	res := w.Result()
@@ -279,13 +367,13 @@ func TestEditSucess(t *testing.T) {
 	assert := assert.New(t)
 
 	product := RandonProduct()
-	req := httptest.NewRequest(http.MethodGet, "/edit?id="+fmt.Sprint(product.Id), nil)
+	req := newRequest(http.MethodGet, "/products/"+fmt.Sprint(product.Id)+"/edit", nil)
 	w := httptest.NewRecorder()
 
 	srv := mocks.NewMockProductModelService(ctrl)
-	pc := NewProductControl(templatePath, srv)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
 
-	srv.EXPECT().Get(fmt.Sprint(product.Id)).Return(product, nil).AnyTimes()
+	srv.EXPECT().Get(gomock.Any(), fmt.Sprint(product.Id)).Return(product, nil).AnyTimes()
 
 	pc.Edit(w, req)
 	res := w.Result()
@@ -301,14 +389,14 @@ func TestEditError(t *testing.T) {
 	assert := assert.New(t)
 
 	product := RandonProduct()
-	req := httptest.NewRequest(http.MethodGet, "/edit?id="+fmt.Sprint(product.Id), nil)
+	req := newRequest(http.MethodGet, "/products/"+fmt.Sprint(product.Id)+"/edit", nil)
 	w := httptest.NewRecorder()
 
 	srv := mocks.NewMockProductModelService(ctrl)
-	pc := NewProductControl(templatePath, srv)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
 
 	errorExpected := errors.New("boom")
-	srv.EXPECT().Get(fmt.Sprint(product.Id)).Return(product, errorExpected).AnyTimes()
+	srv.EXPECT().Get(gomock.Any(), fmt.Sprint(product.Id)).Return(product, errorExpected).AnyTimes()
 
 	pc.Edit(w, req)
 	res := w.Result()
@@ -324,12 +412,11 @@ func TestUpdateSucess(t *testing.T) {
 	assert := assert.New(t)
 
 	product := RandonProduct()
-	req := httptest.NewRequest(http.MethodPost, "/update", nil)
+	req := newRequest(http.MethodPut, "/products/"+fmt.Sprint(product.Id), nil)
 	form := map[string][]string{
-		"id":          {fmt.Sprint(product.Id)},
 		"name":        {product.Name},
 		"description": {product.Description},
-		"value":       {fmt.Sprint(product.Value)},
+		"value":       {product.Value.Decimal()},
 		"quantity":    {fmt.Sprint(product.Quantity)},
 	}
 
@@ -337,8 +424,9 @@ func TestUpdateSucess(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	srv := mocks.NewMockProductModelService(ctrl)
-	pc := NewProductControl(templatePath, srv)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
 	srv.EXPECT().Update(
+		gomock.Any(),
 		product.Id,
 		product.Name,
 		product.Description,
@@ -352,7 +440,7 @@ func TestUpdateSucess(t *testing.T) {
 	_, err := ioutil.ReadAll(res.Body)
 
 	assert.Nil(err)
-	assert.Equal(res.StatusCode, http.StatusMovedPermanently)
+	assert.Equal(res.StatusCode, http.StatusSeeOther)
 }
 
 func TestUpdateBadRequestValue(t *testing.T) {
@@ -362,9 +450,8 @@ func TestUpdateBadRequestValue(t *testing.T) {
 	product := RandonProduct()
 
 	t.Run("Bad Value in field: value", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodPost, "/update", nil)
+		req := newRequest(http.MethodPut, "/products/"+fmt.Sprint(product.Id), nil)
 		form := map[string][]string{
-			"id":          {fmt.Sprint(product.Id)},
 			"name":        {product.Name},
 			"description": {product.Description},
 			"value":       {"value"},
@@ -375,8 +462,9 @@ func TestUpdateBadRequestValue(t *testing.T) {
 		w := httptest.NewRecorder()
 
 		srv := mocks.NewMockProductModelService(ctrl)
-		pc := NewProductControl(templatePath, srv)
+		pc := NewProductControl(templatePath, srv, logging.Discard())
 		srv.EXPECT().Update(
+			gomock.Any(),
 			product.Id,
 			product.Name,
 			product.Description,
@@ -390,16 +478,15 @@ func TestUpdateBadRequestValue(t *testing.T) {
 		_, err := ioutil.ReadAll(res.Body)
 
 		assert.Nil(err)
-		assert.Equal(res.StatusCode, http.StatusBadRequest)
+		assert.Equal(res.StatusCode, http.StatusUnprocessableEntity)
 	})
 
 	t.Run("Bad Value in field: value", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodPost, "/update", nil)
+		req := newRequest(http.MethodPut, "/products/Id", nil)
 		form := map[string][]string{
-			"id":          {"Id"},
 			"name":        {product.Name},
 			"description": {product.Description},
-			"value":       {fmt.Sprint(product.Value)},
+			"value":       {product.Value.Decimal()},
 			"quantity":    {fmt.Sprint(product.Quantity)},
 		}
 
@@ -407,8 +494,9 @@ func TestUpdateBadRequestValue(t *testing.T) {
 		w := httptest.NewRecorder()
 
 		srv := mocks.NewMockProductModelService(ctrl)
-		pc := NewProductControl(templatePath, srv)
+		pc := NewProductControl(templatePath, srv, logging.Discard())
 		srv.EXPECT().Update(
+			gomock.Any(),
 			gomock.Any(),
 			product.Name,
 			product.Description,
@@ -426,12 +514,11 @@ func TestUpdateBadRequestValue(t *testing.T) {
 	})
 
 	t.Run("Bad Value in field: value", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodPost, "/update", nil)
+		req := newRequest(http.MethodPut, "/products/"+fmt.Sprint(product.Id), nil)
 		form := map[string][]string{
-			"id":          {fmt.Sprint(product.Id)},
 			"name":        {product.Name},
 			"description": {product.Description},
-			"value":       {fmt.Sprint(product.Value)},
+			"value":       {product.Value.Decimal()},
 			"quantity":    {"quantity"},
 		}
 
@@ -439,8 +526,9 @@ func TestUpdateBadRequestValue(t *testing.T) {
 		w := httptest.NewRecorder()
 
 		srv := mocks.NewMockProductModelService(ctrl)
-		pc := NewProductControl(templatePath, srv)
+		pc := NewProductControl(templatePath, srv, logging.Discard())
 		srv.EXPECT().Update(
+			gomock.Any(),
 			product.Id,
 			product.Name,
 			product.Description,
@@ -454,7 +542,7 @@ func TestUpdateBadRequestValue(t *testing.T) {
 		_, err := ioutil.ReadAll(res.Body)
 
 		assert.Nil(err)
-		assert.Equal(res.StatusCode, http.StatusBadRequest)
+		assert.Equal(res.StatusCode, http.StatusUnprocessableEntity)
 	})
 }
 
@@ -463,12 +551,11 @@ func TestUpdateError(t *testing.T) {
 	assert := assert.New(t)
 
 	product := RandonProduct()
-	req := httptest.NewRequest(http.MethodPost, "/update", nil)
+	req := newRequest(http.MethodPut, "/products/"+fmt.Sprint(product.Id), nil)
 	form := map[string][]string{
-		"id":          {fmt.Sprint(product.Id)},
 		"name":        {product.Name},
 		"description": {product.Description},
-		"value":       {fmt.Sprint(product.Value)},
+		"value":       {product.Value.Decimal()},
 		"quantity":    {fmt.Sprint(product.Quantity)},
 	}
 
@@ -477,8 +564,9 @@ func TestUpdateError(t *testing.T) {
 	errorExpected := errors.New("boom")
 
 	srv := mocks.NewMockProductModelService(ctrl)
-	pc := NewProductControl(templatePath, srv)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
 	srv.EXPECT().Update(
+		gomock.Any(),
 		product.Id,
 		product.Name,
 		product.Description,
@@ -494,3 +582,277 @@ func TestUpdateError(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(res.StatusCode, http.StatusInternalServerError)
 }
+
+func TestDomainErrorStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	p := RandonProduct()
+	srv := mocks.NewMockProductModelService(ctrl)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
+
+	t.Run("Edit of missing product", func(t *testing.T) {
+		req := newRequest(http.MethodGet, "/products/"+fmt.Sprint(p.Id)+"/edit", nil)
+		w := httptest.NewRecorder()
+		srv.EXPECT().Get(gomock.Any(), fmt.Sprint(p.Id)).Return(p, product.ErrNotFound)
+
+		pc.Edit(w, req)
+
+		assert.Equal(http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Delete of missing product", func(t *testing.T) {
+		req := newRequest(http.MethodDelete, "/products/"+fmt.Sprint(p.Id), nil)
+		w := httptest.NewRecorder()
+		srv.EXPECT().Delete(gomock.Any(), fmt.Sprint(p.Id)).Return(product.ErrNotFound)
+
+		pc.Delete(w, req)
+
+		assert.Equal(http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Insert of conflicting product", func(t *testing.T) {
+		req := newRequest(http.MethodPost, "/products", nil)
+		req.Form = map[string][]string{
+			"name":        {p.Name},
+			"description": {p.Description},
+			"value":       {p.Value.Decimal()},
+			"quantity":    {fmt.Sprint(p.Quantity)},
+		}
+		w := httptest.NewRecorder()
+		srv.EXPECT().Create(gomock.Any(), p.Name, p.Description, p.Value, p.Quantity).Return(0, product.ErrConflict)
+
+		pc.Insert(w, req)
+
+		assert.Equal(http.StatusConflict, w.Code)
+	})
+
+	t.Run("Update of invalid product", func(t *testing.T) {
+		req := newRequest(http.MethodPut, "/products/"+fmt.Sprint(p.Id), nil)
+		req.Form = map[string][]string{
+			"name":        {p.Name},
+			"description": {p.Description},
+			"value":       {p.Value.Decimal()},
+			"quantity":    {fmt.Sprint(p.Quantity)},
+		}
+		w := httptest.NewRecorder()
+		srv.EXPECT().Update(gomock.Any(), p.Id, p.Name, p.Description, p.Value, p.Quantity).Return(product.ErrInvalid)
+
+		pc.Update(w, req)
+
+		assert.Equal(http.StatusUnprocessableEntity, w.Code)
+	})
+}
+
+func TestFormValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	srv := mocks.NewMockProductModelService(ctrl)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
+
+	t.Run("Insert shows submitted values and field errors", func(t *testing.T) {
+		req := newRequest(http.MethodPost, "/products", nil)
+		req.Form = map[string][]string{
+			"name":        {"  "},
+			"description": {"kept description"},
+			"value":       {"1.234"},
+			"quantity":    {"-1"},
+		}
+		w := httptest.NewRecorder()
+
+		pc.Insert(w, req)
+		body := w.Body.String()
+
+		assert.Equal(http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(body, `value="kept description"`)
+		assert.Contains(body, `value="1.234"`)
+		assert.Contains(body, "Name is required")
+		assert.Contains(body, "Price must have at most 2 decimal places")
+		assert.Contains(body, "Quantity must not be negative")
+		assert.Contains(body, `<option value="BRL" selected>`)
+	})
+
+	t.Run("Update shows validation errors from the service", func(t *testing.T) {
+		req := newRequest(http.MethodPut, "/products/3", nil)
+		req.Form = map[string][]string{
+			"name":        {"pen"},
+			"description": {""},
+			"value":       {"2.50"},
+			"quantity":    {"1"},
+		}
+		w := httptest.NewRecorder()
+		srv.EXPECT().Update(gomock.Any(), 3, "pen", "", money.New(250, "BRL"), 1).
+			Return(product.ValidationErrors{"name": "is already taken"})
+
+		pc.Update(w, req)
+		body := w.Body.String()
+
+		assert.Equal(http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(body, `value="pen"`)
+		assert.Contains(body, "Name is already taken")
+	})
+}
+
+func TestFailureLogs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	logs := &bytes.Buffer{}
+	logger, _ := logging.New(logs, "json", "info")
+	srv := mocks.NewMockProductModelService(ctrl)
+	pc := NewProductControl(templatePath, srv, logger)
+
+	req := newRequest(http.MethodDelete, "/products/7", nil)
+	req = req.WithContext(middleware.WithRequestID(req.Context(), "req-7"))
+	srv.EXPECT().Delete(gomock.Any(), "7").Return(product.ErrNotFound)
+	srv.EXPECT().Delete(gomock.Any(), "7").Return(errors.New("boom"))
+
+	pc.Delete(httptest.NewRecorder(), req)
+	pc.Delete(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	assert.Len(lines, 2)
+
+	for i, level := range []string{"WARN", "ERROR"} {
+		record := map[string]interface{}{}
+		assert.Nil(json.Unmarshal([]byte(lines[i]), &record))
+		assert.Equal(level, record["level"])
+		assert.Equal("product.delete.failed", record["msg"])
+		assert.Equal("7", record["product_id"])
+		assert.Equal("req-7", record["request_id"])
+		assert.NotEmpty(record["error"])
+	}
+}
+
+// as signs req in as a user with role.
+func as(req *http.Request, role user.Role) *http.Request {
+	return req.WithContext(middleware.WithUser(req.Context(), user.User{Id: 1, Username: string(role), Role: role}))
+}
+
+func TestIndexHidesActions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	srv := mocks.NewMockProductModelService(ctrl)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
+	p := RandonProduct()
+	srv.EXPECT().ListProducts(gomock.Any(), gomock.Any()).Return(product.Page{Products: []product.Product{p}, Total: 1, Limit: product.DefaultPageSize}, nil).AnyTimes()
+
+	edit := fmt.Sprintf(`href="/products/%d/edit"`, p.Id)
+	for _, tc := range []struct {
+		role                 user.Role
+		create, edit, delete bool
+	}{
+		{user.Viewer, false, false, false},
+		{user.Clerk, false, true, false},
+		{user.Manager, true, true, true},
+		{user.Admin, true, true, true},
+	} {
+		w := httptest.NewRecorder()
+		pc.Index(w, as(httptest.NewRequest(http.MethodGet, "/", nil), tc.role))
+		body := w.Body.String()
+
+		assert.Equal(tc.create, strings.Contains(body, `href="/products/new"`), tc.role)
+		assert.Equal(tc.edit, strings.Contains(body, edit), tc.role)
+		assert.Equal(tc.delete, strings.Contains(body, `value="DELETE"`), tc.role)
+		assert.Contains(body, `<span class="navbar-text mr-2">`+string(tc.role)+`</span>`)
+	}
+}
+
+func TestUpdateAsClerk(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	srv := mocks.NewMockProductModelService(ctrl)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
+	p := RandonProduct()
+	id := fmt.Sprint(p.Id)
+
+	update := func(form url.Values) *httptest.ResponseRecorder {
+		req := as(newRequest(http.MethodPut, "/products/"+id, nil), user.Clerk)
+		req.Form = form
+		w := httptest.NewRecorder()
+		pc.Update(w, req)
+		return w
+	}
+
+	t.Run("Testing quantity only", func(t *testing.T) {
+		srv.EXPECT().Get(gomock.Any(), id).Return(p, nil)
+		srv.EXPECT().SetQuantity(gomock.Any(), p.Id, 7).Return(nil)
+
+		w := update(url.Values{"quantity": {"7"}})
+		assert.Equal(http.StatusSeeOther, w.Code)
+	})
+
+	t.Run("Testing unchanged details", func(t *testing.T) {
+		srv.EXPECT().Get(gomock.Any(), id).Return(p, nil)
+		srv.EXPECT().SetQuantity(gomock.Any(), p.Id, 8).Return(nil)
+
+		w := update(url.Values{"name": {p.Name}, "value": {p.Value.Decimal()}, "currency": {p.Value.Currency}, "quantity": {"8"}})
+		assert.Equal(http.StatusSeeOther, w.Code)
+	})
+
+	t.Run("Testing changed details", func(t *testing.T) {
+		for _, form := range []url.Values{
+			{"name": {p.Name + "!"}, "quantity": {"7"}},
+			{"description": {"cheaper"}, "quantity": {"7"}},
+			{"value": {"0.01"}, "quantity": {"7"}},
+		} {
+			srv.EXPECT().Get(gomock.Any(), id).Return(p, nil)
+
+			w := update(form)
+			assert.Equal(http.StatusForbidden, w.Code, form.Encode())
+		}
+	})
+
+	t.Run("Testing missing product", func(t *testing.T) {
+		srv.EXPECT().Get(gomock.Any(), id).Return(product.Product{}, product.ErrNotFound)
+
+		w := update(url.Values{"quantity": {"7"}})
+		assert.Equal(http.StatusNotFound, w.Code)
+	})
+}
+
+func TestEditAsClerk(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	srv := mocks.NewMockProductModelService(ctrl)
+	pc := NewProductControl(templatePath, srv, logging.Discard())
+	p := RandonProduct()
+	srv.EXPECT().Get(gomock.Any(), fmt.Sprint(p.Id)).Return(p, nil).Times(2)
+
+	w := httptest.NewRecorder()
+	pc.Edit(w, as(newRequest(http.MethodGet, fmt.Sprintf("/products/%d/edit", p.Id), nil), user.Clerk))
+	assert.Contains(w.Body.String(), `id="name" maxlength="255" class="form-control " required disabled>`)
+	assert.Regexp(`id="quantity" [^>]*required>`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	pc.Edit(w, as(newRequest(http.MethodGet, fmt.Sprintf("/products/%d/edit", p.Id), nil), user.Manager))
+	assert.NotContains(w.Body.String(), "disabled")
+}
+
+func TestHistory(t *testing.T) {
+	assert := assert.New(t)
+	svc := product.NewMemoryProductModelService()
+	pc := NewProductControl(templatePath, svc, logging.Discard())
+
+	ctx := product.WithActor(context.Background(), product.Actor{Name: "user:ana", RequestId: "req-1"})
+	id, _ := svc.Create(ctx, "pen", "", money.New(250, "BRL"), 10)
+	svc.Update(ctx, id, "pen", "", money.New(300, "BRL"), 10)
+
+	w := httptest.NewRecorder()
+	pc.History(w, newRequest(http.MethodGet, "/products/1/history", nil))
+
+	body := w.Body.String()
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Contains(body, "History of product #1")
+	assert.Contains(body, "user:ana")
+	assert.Contains(body, "<code>req-1</code>")
+	assert.Contains(body, "<strong>value</strong>: <del>R$ 2,50</del> &rarr; R$ 3,00")
+
+	w = httptest.NewRecorder()
+	pc.History(w, newRequest(http.MethodGet, "/products/2/history", nil))
+	assert.Equal(http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/model/product"
+	"github.com/silastgoes/mock-store/src/money"
+	"github.com/stretchr/testify/assert"
+)
+
+// xssPayloads break out of the contexts the pages print user data in: text,
+// quoted attributes and inline script.
+var xssPayloads = []string{
+	`<script>alert(1)</script>`,
+	`"><img src=x onerror=alert(1)>`,
+	`'><svg onload=alert(1)>`,
+	`'); alert(1); //`,
+	`</textarea><iframe src="javascript:alert(1)">`,
+}
+
+func TestTemplatesEscapeStoredProducts(t *testing.T) {
+	assert := assert.New(t)
+
+	store := product.NewMemoryProductModelService()
+	pc := NewProductControl(templatePath, store, logging.Discard())
+
+	for _, payload := range xssPayloads {
+		id, err := store.Create(context.Background(), payload, "about "+payload, money.New(100, money.DefaultCurrency), 1)
+		assert.Nil(err)
+
+		t.Run("Testing Edit with "+payload, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			pc.Edit(w, newRequest(http.MethodGet, fmt.Sprintf("/products/%d/edit", id), nil))
+
+			assert.Equal(http.StatusOK, w.Code)
+			assert.NotContains(w.Body.String(), payload)
+			assert.Contains(w.Body.String(), `value="`+html.EscapeString(payload)+`"`)
+		})
+	}
+
+	t.Run("Testing Index", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		pc.Index(w, newRequest(http.MethodGet, "/?limit=100", nil))
+
+		assert.Equal(http.StatusOK, w.Code)
+		for _, payload := range xssPayloads {
+			assert.NotContains(w.Body.String(), payload)
+			assert.Contains(w.Body.String(), "<td>"+html.EscapeString(payload)+"</td>")
+		}
+	})
+}
+
+func TestTemplatesEscapeRejectedForms(t *testing.T) {
+	assert := assert.New(t)
+	pc := NewProductControl(templatePath, product.NewMemoryProductModelService(), logging.Discard())
+
+	for _, payload := range xssPayloads {
+		req := newRequest(http.MethodPost, "/products", nil)
+		req.Form = url.Values{"name": {payload}, "description": {payload}, "value": {payload}, "currency": {"BRL"}, "quantity": {"1"}}
+		w := httptest.NewRecorder()
+		pc.Insert(w, req)
+
+		assert.Equal(http.StatusUnprocessableEntity, w.Code, payload)
+		assert.NotContains(w.Body.String(), payload)
+	}
+}
+
+func TestTemplatesEscapeSearch(t *testing.T) {
+	assert := assert.New(t)
+	pc := NewProductControl(templatePath, product.NewMemoryProductModelService(), logging.Discard())
+
+	for _, payload := range xssPayloads {
+		w := httptest.NewRecorder()
+		pc.Index(w, newRequest(http.MethodGet, "/?q="+url.QueryEscape(payload), nil))
+
+		assert.Equal(http.StatusOK, w.Code, payload)
+		assert.NotContains(w.Body.String(), payload)
+	}
+}
@@ -1,29 +1,140 @@
 package dbconnection
 
 import (
+	"context"
 	"database/sql"
-	"os"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/config"
+	"github.com/silastgoes/mock-store/src/logging"
 
 	_ "github.com/lib/pq"
 )
 
-type DatabadeConnection struct{}
+// maxConnectBackoff caps the wait between two startup pings.
+var maxConnectBackoff = 10 * time.Second
+
+type DatabadeConnection struct {
+	Config config.Database
+	Logger *slog.Logger
+	db     *sql.DB
+}
 
 //go:generate mockgen --source=dbconnection.go --package=mocks --destination=./mocks/dbconnection.go  DbConnectionService
 type DbConnectionService interface {
 	GetDb() (*sql.DB, error)
+	Connect(ctx context.Context) (*sql.DB, error)
+	Ping(ctx context.Context) error
+	Stats() sql.DBStats
 }
 
-func NewDatabadeConnection() *DatabadeConnection {
-	return &DatabadeConnection{}
+func NewDatabadeConnection(cfg config.Database, logger *slog.Logger) *DatabadeConnection {
+	return &DatabadeConnection{Config: cfg, Logger: logger}
 }
 
+// GetDb returns the pool, opening it with the configured limits on first
+// use. It does not connect; see Connect.
 func (dbc *DatabadeConnection) GetDb() (*sql.DB, error) {
-	source := "user=" + os.Getenv("POSTGRES_USER") +
-		" dbname=" + os.Getenv("POSTGRES_NAME") +
-		" password=" + os.Getenv("POSTGRES_PASSWORD") +
-		" host=" + os.Getenv("POSTGRES_HOST") +
-		" sslmode=d" + os.Getenv("POSTGRES_SSLMODE")
+	if dbc.db != nil {
+		return dbc.db, nil
+	}
+
+	db, err := sql.Open("postgres", dbc.DSN())
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(dbc.Config.MaxOpenConns)
+	db.SetMaxIdleConns(dbc.Config.MaxIdleConns)
+	db.SetConnMaxLifetime(dbc.Config.ConnMaxLifetime)
+
+	dbc.db = db
+	return db, nil
+}
+
+// Connect returns the pool once the database answers a ping. A failed ping
+// is retried up to ConnectAttempts times, waiting ConnectBackoff and then
+// twice as long each time, so the app can start alongside its database
+// without serving a single failing request.
+func (dbc *DatabadeConnection) Connect(ctx context.Context) (*sql.DB, error) {
+	db, err := dbc.GetDb()
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := dbc.Config.ConnectAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := dbc.Config.ConnectBackoff
+
+	for attempt := 1; ; attempt++ {
+		if err = db.PingContext(ctx); err == nil {
+			break
+		}
+		if attempt == attempts {
+			return nil, fmt.Errorf("database unreachable after %d attempts: %w", attempts, err)
+		}
+
+		dbc.Logger.WarnContext(ctx, logging.DbPingFailed,
+			"attempt", attempt,
+			"retry_in", backoff,
+			"error", err,
+		)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(2*backoff, maxConnectBackoff)
+	}
+
+	dbc.Logger.InfoContext(ctx, logging.DbConnected,
+		"host", dbc.Config.Host,
+		"name", dbc.Config.Name,
+		"max_open_conns", dbc.Config.MaxOpenConns,
+		"max_idle_conns", dbc.Config.MaxIdleConns,
+	)
+	return db, nil
+}
+
+// Ping checks the database still answers.
+func (dbc *DatabadeConnection) Ping(ctx context.Context) error {
+	if dbc.db == nil {
+		return errors.New("database not connected")
+	}
+
+	return dbc.db.PingContext(ctx)
+}
+
+// Stats reports the pool's usage, or zeros when it was never opened.
+func (dbc *DatabadeConnection) Stats() sql.DBStats {
+	if dbc.db == nil {
+		return sql.DBStats{}
+	}
+
+	return dbc.db.Stats()
+}
+
+// DSN is the connection URL for the configured database. Building it as a
+// URL keeps passwords with spaces or quotes intact.
+func (dbc *DatabadeConnection) DSN() string {
+	cfg := dbc.Config
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:     "/" + cfg.Name,
+		RawQuery: url.Values{"sslmode": {cfg.SSLMode}}.Encode(),
+	}
 
-	return sql.Open("postgres", source)
+	return dsn.String()
 }
@@ -1,13 +1,119 @@
 package dbconnection
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/silastgoes/mock-store/src/config"
+	"github.com/silastgoes/mock-store/src/logging"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGetDb(t *testing.T) {
-	_, err := NewDatabadeConnection().GetDb()
+	_, err := NewDatabadeConnection(config.Default().Database, logging.Discard()).GetDb()
 	assert := assert.New(t)
 	assert.NoError(err)
 }
+
+func TestDSN(t *testing.T) {
+	dbc := NewDatabadeConnection(config.Database{
+		User:     "store",
+		Password: "p@ss word",
+		Name:     "mock",
+		Host:     "db",
+		Port:     5433,
+		SSLMode:  "disable",
+	}, logging.Discard())
+
+	assert.Equal(t, "postgres://store:p%40ss%20word@db:5433/mock?sslmode=disable", dbc.DSN())
+}
+
+func TestPoolSettings(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.MaxOpenConns = 3
+	dbc := NewDatabadeConnection(cfg, logging.Discard())
+
+	db, err := dbc.GetDb()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	again, _ := dbc.GetDb()
+	assert.Same(t, db, again)
+	assert.Equal(t, 3, dbc.Stats().MaxOpenConnections)
+}
+
+func TestConnect(t *testing.T) {
+	assert := assert.New(t)
+
+	connection := func(t *testing.T, attempts int) (*DatabadeConnection, sqlmock.Sqlmock, *bytes.Buffer) {
+		db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		assert.Nil(err)
+		t.Cleanup(func() { db.Close() })
+
+		buf := &bytes.Buffer{}
+		logger, _ := logging.New(buf, "text", "info")
+
+		cfg := config.Default().Database
+		cfg.ConnectAttempts = attempts
+		cfg.ConnectBackoff = time.Millisecond
+		dbc := NewDatabadeConnection(cfg, logger)
+		dbc.db = db
+		return dbc, mock, buf
+	}
+
+	t.Run("Testing retry until the database answers", func(t *testing.T) {
+		dbc, mock, buf := connection(t, 3)
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		mock.ExpectPing()
+
+		db, err := dbc.Connect(context.Background())
+
+		assert.Nil(err)
+		assert.NotNil(db)
+		assert.Nil(mock.ExpectationsWereMet())
+		assert.Contains(buf.String(), `level=WARN msg=db.ping.failed attempt=1 retry_in=1ms error="connection refused"`)
+		assert.Contains(buf.String(), `level=WARN msg=db.ping.failed attempt=2 retry_in=2ms`)
+		assert.Contains(buf.String(), "level=INFO msg=db.connected")
+	})
+
+	t.Run("Testing give up after the last attempt", func(t *testing.T) {
+		dbc, mock, _ := connection(t, 2)
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		mock.ExpectPing().WillReturnError(errors.New("no route to host"))
+
+		_, err := dbc.Connect(context.Background())
+
+		assert.EqualError(err, "database unreachable after 2 attempts: no route to host")
+		assert.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("Testing canceled while waiting", func(t *testing.T) {
+		dbc, mock, _ := connection(t, 5)
+		dbc.Config.ConnectBackoff = time.Hour
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		_, err := dbc.Connect(ctx)
+
+		assert.ErrorIs(err, context.Canceled)
+	})
+}
+
+func TestPing(t *testing.T) {
+	dbc := NewDatabadeConnection(config.Default().Database, logging.Discard())
+	assert.EqualError(t, dbc.Ping(context.Background()), "database not connected")
+
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	assert.Nil(t, err)
+	defer db.Close()
+	dbc.db = db
+
+	mock.ExpectPing().WillReturnError(errors.New("connection reset"))
+	assert.EqualError(t, dbc.Ping(context.Background()), "connection reset")
+}
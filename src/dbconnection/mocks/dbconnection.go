@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	sql "database/sql"
 	reflect "reflect"
 
@@ -34,6 +35,21 @@ func (m *MockDbConnectionService) EXPECT() *MockDbConnectionServiceMockRecorder
 	return m.recorder
 }
 
+// Connect mocks base method.
+func (m *MockDbConnectionService) Connect(ctx context.Context) (*sql.DB, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Connect", ctx)
+	ret0, _ := ret[0].(*sql.DB)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Connect indicates an expected call of Connect.
+func (mr *MockDbConnectionServiceMockRecorder) Connect(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Connect", reflect.TypeOf((*MockDbConnectionService)(nil).Connect), ctx)
+}
+
 // GetDb mocks base method.
 func (m *MockDbConnectionService) GetDb() (*sql.DB, error) {
 	m.ctrl.T.Helper()
@@ -48,3 +64,31 @@ func (mr *MockDbConnectionServiceMockRecorder) GetDb() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDb", reflect.TypeOf((*MockDbConnectionService)(nil).GetDb))
 }
+
+// Ping mocks base method.
+func (m *MockDbConnectionService) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockDbConnectionServiceMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockDbConnectionService)(nil).Ping), ctx)
+}
+
+// Stats mocks base method.
+func (m *MockDbConnectionService) Stats() sql.DBStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(sql.DBStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockDbConnectionServiceMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockDbConnectionService)(nil).Stats))
+}
@@ -0,0 +1,129 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Event names are stable keys the log pipeline can match on; they go in the
+// message of every record.
+const (
+	HttpRequest         = "http.request"
+	HttpPanic           = "http.panic"
+	HttpWriteFailed     = "http.write.failed"
+	CSRFRejected        = "csrf.rejected"
+	HealthCheckFailed   = "health.check.failed"
+	ServerStarted       = "server.started"
+	ServerStopping      = "server.stopping"
+	ServerStopped       = "server.stopped"
+	ServerFailed        = "server.failed"
+	DbConnected         = "db.connected"
+	DbPingFailed        = "db.ping.failed"
+	DbPoolStats         = "db.pool.stats"
+	DbCloseFailed       = "db.close.failed"
+	MigrateFailed       = "migrate.failed"
+	TracingFailed       = "tracing.failed"
+	ProductListFailed   = "product.list.failed"
+	ProductGetFailed    = "product.get.failed"
+	ProductCreateFailed = "product.create.failed"
+	ProductUpdateFailed = "product.update.failed"
+	ProductDeleteFailed = "product.delete.failed"
+	ProductAuditFailed  = "product.audit.failed"
+	ProductInvalid      = "product.invalid"
+	ProductQueryFailed  = "product.query.failed"
+	UserQueryFailed     = "user.query.failed"
+	UserCreated         = "user.created"
+	UserCreateFailed    = "user.create.failed"
+	LoginSucceeded      = "auth.login.succeeded"
+	LoginFailed         = "auth.login.failed"
+	Logout              = "auth.logout"
+	SessionFailed       = "auth.session.failed"
+	AccessDenied        = "auth.access.denied"
+	APIKeyRejected      = "auth.api_key.rejected"
+	APIKeyCreated       = "auth.api_key.created"
+	APIKeyRevoked       = "auth.api_key.revoked"
+	APIKeyFailed        = "auth.api_key.failed"
+	APIKeyQueryFailed   = "api_key.query.failed"
+	OIDCDiscovered      = "auth.oidc.discovered"
+	OIDCFailed          = "auth.oidc.failed"
+	SessionKeyGenerated = "auth.session_key.generated"
+)
+
+// New builds a logger writing to w in the given format, "json" or "text"
+// (the default), dropping records below level ("debug", "info" (the
+// default), "warn" or "error"). Records logged with a context carry the
+// attributes added to it by With.
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+
+	return slog.New(contextHandler{h}), nil
+}
+
+// Discard is a logger that drops everything, for tests.
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+func ParseLevel(s string) (slog.Level, error) {
+	var lvl slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+
+	if err := lvl.UnmarshalText([]byte(s)); err != nil {
+		return lvl, fmt.Errorf("unknown log level %q", s)
+	}
+
+	return lvl, nil
+}
+
+type attrsKey struct{}
+
+// With returns a copy of ctx whose log records will also carry attrs, such
+// as the request id of the request being served.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	current, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(current)+len(attrs))
+	merged = append(append(merged, current...), attrs...)
+
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
+
+// contextHandler adds the attributes stored in the record's context by With.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
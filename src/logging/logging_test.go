@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := &bytes.Buffer{}
+	logger, err := New(buf, "json", "warn")
+	assert.Nil(err)
+
+	ctx := With(context.Background(), slog.String("request_id", "req-1"))
+	ctx = With(ctx, slog.String("user", "ana"))
+	logger.InfoContext(ctx, "dropped")
+	logger.With("product_id", 3).WarnContext(ctx, ProductUpdateFailed, "error", "boom")
+
+	record := map[string]interface{}{}
+	assert.Nil(json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal("WARN", record["level"])
+	assert.Equal("product.update.failed", record["msg"])
+	assert.Equal(float64(3), record["product_id"])
+	assert.Equal("boom", record["error"])
+	assert.Equal("req-1", record["request_id"])
+	assert.Equal("ana", record["user"])
+}
+
+func TestNewText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger, err := New(buf, "", "")
+	assert.Nil(t, err)
+
+	logger.Debug("dropped")
+	logger.Info(HttpRequest, "status", 200)
+
+	assert.Regexp(t, `^time=\S+ level=INFO msg=http.request status=200\n$`, buf.String())
+}
+
+func TestNewRejectsBadSettings(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := New(&bytes.Buffer{}, "xml", "info")
+	assert.Error(err)
+
+	_, err = New(&bytes.Buffer{}, "json", "loud")
+	assert.Error(err)
+}
+
+func TestParseLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	for in, expected := range map[string]slog.Level{
+		"":      slog.LevelInfo,
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		lvl, err := ParseLevel(in)
+		assert.Nil(err, in)
+		assert.Equal(expected, lvl, in)
+	}
+}
@@ -1,38 +1,339 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/silastgoes/mock-store/src/config"
 	"github.com/silastgoes/mock-store/src/controllers"
 	"github.com/silastgoes/mock-store/src/dbconnection"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/metrics"
+	"github.com/silastgoes/mock-store/src/middleware"
+	"github.com/silastgoes/mock-store/src/migrations"
+	"github.com/silastgoes/mock-store/src/model/apikey"
 	"github.com/silastgoes/mock-store/src/model/product"
+	"github.com/silastgoes/mock-store/src/model/user"
+	"github.com/silastgoes/mock-store/src/oidc"
+	"github.com/silastgoes/mock-store/src/tracing"
 
 	rts "github.com/silastgoes/mock-store/src/routes"
 )
 
-var (
-	templatePath = "templates/*.html"
-)
+var templatePath = "templates/*.html"
+
+// version names the build in /status; release builds set it with
+// -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+// traceFlushTimeout bounds how long exiting waits for pending spans.
+var traceFlushTimeout = 5 * time.Second
+
+// oidcTimeout bounds each call to the single sign-on provider.
+var oidcTimeout = 10 * time.Second
+
+func main() {
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+
+	if len(args) > 0 && args[0] == "useradd" {
+		if cfg.Store != "postgres" {
+			fmt.Fprintln(os.Stderr, "useradd needs the postgres store")
+			os.Exit(2)
+		}
+
+		db, err := dbconnection.NewDatabadeConnection(cfg.Database, logger).Connect(context.Background())
+		if err != nil {
+			logger.Error(logging.UserCreateFailed, "error", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		err = AddUser(context.Background(), user.NewUserModelService(db, logger), args[1:], os.Stdin, os.Stdout)
+		if err != nil {
+			logger.Error(logging.UserCreateFailed, "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "migrate" {
+		if cfg.Store != "postgres" {
+			fmt.Fprintln(os.Stderr, "migrate needs the postgres store")
+			os.Exit(2)
+		}
+
+		db, err := dbconnection.NewDatabadeConnection(cfg.Database, logger).Connect(context.Background())
+		if err != nil {
+			logger.Error(logging.MigrateFailed, "error", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		err = Migrate(context.Background(), migrations.NewMigrationService(db), args[1:], os.Stdout)
+		if err != nil {
+			logger.Error(logging.MigrateFailed, "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	flushTraces, err := tracing.Setup(ctx, cfg.Tracing, version, os.Stdout)
+	if err != nil {
+		logger.Error(logging.TracingFailed, "error", err)
+		os.Exit(1)
+	}
+
+	err = run(ctx, cfg, logger)
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), traceFlushTimeout)
+	defer cancel()
+	if err := flushTraces(flushCtx); err != nil {
+		logger.Error(logging.TracingFailed, "error", err)
+	}
 
-func init() {
-	err := godotenv.Load(".env")
 	if err != nil {
+		logger.Error(logging.ServerFailed, "error", err)
+		os.Exit(1)
+	}
+}
+
+// run serves the store until ctx is done, then drains in-flight requests
+// and only afterwards closes the database they may still be using.
+func run(ctx context.Context, cfg config.Config, logger *slog.Logger) error {
+	var handler http.Handler
+	m := metrics.New(logger)
+	key := sessionKey(cfg.Auth, logger)
+	provider, err := discoverOIDC(ctx, cfg.OIDC, logger)
+	if err != nil {
+		return err
+	}
+	if cfg.Store == "memory" {
+		srv := product.NewMemoryProductModelService()
+		users := user.NewMemoryUserModelService()
+		if err := addAdmin(ctx, users, cfg.Auth, logger); err != nil {
+			return err
+		}
+
+		m.WatchInventory(srv)
+		sessions := middleware.NewSessions(users, key, cfg.Auth.SessionTTL, logger)
+		keys := apikey.NewMemoryAPIKeyModelService()
+		handler = LoadControlles(tracing.Products(srv, ""), users, keys, sessions, provider, nil, nil, m, logger)
+	} else {
+		conn := dbconnection.NewDatabadeConnection(cfg.Database, logger)
+		db, err := conn.Connect(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			logPoolStats(logger, conn.Stats())
+			if err := db.Close(); err != nil {
+				logger.Error(logging.DbCloseFailed, "error", err)
+			}
+		}()
+
+		srv := product.NewProductModelService(db, logger)
+		srv.Timeout = cfg.Database.Timeout
+		users := user.NewUserModelService(db, logger)
+		users.Timeout = cfg.Database.Timeout
+		keys := apikey.NewAPIKeyModelService(db, logger)
+		keys.Timeout = cfg.Database.Timeout
+		if err := addAdmin(ctx, users, cfg.Auth, logger); err != nil {
+			return err
+		}
+
+		m.WatchDB(db, cfg.Database.Name)
+		m.WatchInventory(srv)
+		sessions := middleware.NewSessions(users, key, cfg.Auth.SessionTTL, logger)
+		handler = LoadControlles(tracing.Products(srv, "postgresql"), users, keys, sessions, provider, conn, migrations.NewMigrationService(db), m, logger)
+	}
+
+	return Serve(ctx, newServer(cfg.HTTP, handler, logger), cfg.HTTP.ShutdownTimeout, logger)
+}
+
+// logPoolStats records how the connection pool was used over the run.
+func logPoolStats(logger *slog.Logger, stats sql.DBStats) {
+	logger.Info(logging.DbPoolStats,
+		"max_open", stats.MaxOpenConnections,
+		"open", stats.OpenConnections,
+		"in_use", stats.InUse,
+		"idle", stats.Idle,
+		"wait_count", stats.WaitCount,
+		"wait_duration", stats.WaitDuration,
+		"max_idle_closed", stats.MaxIdleClosed,
+		"max_lifetime_closed", stats.MaxLifetimeClosed,
+	)
+}
+
+// sessionKey returns the configured session key, or a random one when none
+// is set, which signs everyone out on every restart.
+func sessionKey(cfg config.Auth, logger *slog.Logger) []byte {
+	if cfg.SessionKey != "" {
+		return []byte(cfg.SessionKey)
+	}
+
+	logger.Warn(logging.SessionKeyGenerated)
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
 		panic(err)
 	}
+
+	return key
 }
 
-func main() {
-	db, _ := dbconnection.NewDatabadeConnection().GetDb()
-	defer db.Close()
-	LoadControlles(db)
-	log.Fatal(http.ListenAndServe(":4444", nil))
+// addAdmin creates the configured startup user unless it already exists.
+func addAdmin(ctx context.Context, users user.UserModelService, cfg config.Auth, logger *slog.Logger) error {
+	if cfg.AdminUser == "" {
+		return nil
+	}
+
+	_, err := users.Create(ctx, cfg.AdminUser, cfg.AdminPassword, user.Admin)
+	if errors.Is(err, user.ErrConflict) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", cfg.AdminUser, err)
+	}
+
+	logger.Info(logging.UserCreated, "username", cfg.AdminUser)
+	return nil
+}
+
+// discoverOIDC returns the configured single sign-on provider, or nil when
+// there is none.
+func discoverOIDC(ctx context.Context, cfg config.OIDC, logger *slog.Logger) (*oidc.Provider, error) {
+	if cfg.Issuer == "" {
+		return nil, nil
+	}
+
+	provider, err := oidc.Discover(ctx, cfg, &http.Client{Timeout: oidcTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", cfg.Issuer, err)
+	}
+
+	logger.Info(logging.OIDCDiscovered, "issuer", cfg.Issuer)
+	return provider, nil
+}
+
+// LoadControlles builds the app's handler, timing srv whatever store backs
+// it. conn and mig are nil for stores without a database, and provider is
+// nil without single sign-on.
+func LoadControlles(srv product.ProductModelService, users user.UserModelService, keys apikey.APIKeyModelService, sessions *middleware.Sessions, provider *oidc.Provider, conn dbconnection.DbConnectionService, mig migrations.MigrationService, m *metrics.Metrics, logger *slog.Logger) http.Handler {
+	srv = m.InstrumentProducts(srv)
+
+	pc := controllers.NewProductControl(templatePath, srv, logger)
+	api := controllers.NewProductApiControl(srv, logger)
+	health := controllers.NewHealthControl(version, pc, conn, mig, logger)
+	auth := controllers.NewAuthControl(templatePath, users, sessions, logger)
+	apiKeys := controllers.NewAPIKeyControl(templatePath, keys, logger)
+
+	var sso controllers.OIDCControlService
+	if provider != nil {
+		auth.SSO = true
+		sso = controllers.NewOIDCControl(templatePath, provider, users, sessions, logger)
+	}
+
+	return rts.NewRouterService(pc, api, health, auth, sso, apiKeys, keys, sessions, m, logger).LoadRoutes()
 }
 
-func LoadControlles(db *sql.DB) {
-	srv := product.NewProductModelService(db)
-	pc := controllers.NewProductControl(templatePath, srv)
-	rts.NewRouterService(pc).LoadRoutes()
+// AddUser runs the useradd subcommand: "useradd [-role r] <username>"
+// creates the user, a viewer unless -role says otherwise, with the password
+// read from the first line of in.
+func AddUser(ctx context.Context, users user.UserModelService, args []string, in io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("useradd", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	roleName := fs.String("role", string(user.Viewer), "viewer, clerk, manager or admin")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("useradd: %w", err)
+	}
+	if fs.NArg() != 1 {
+		return errors.New("useradd: want exactly one username")
+	}
+
+	role, err := user.ParseRole(*roleName)
+	if err != nil {
+		return err
+	}
+
+	password, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	username := fs.Arg(0)
+	id, err := users.Create(ctx, username, strings.TrimRight(password, "\r\n"), role)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "created %s %s (id %d)\n", role, strings.ToLower(username), id)
+	return nil
+}
+
+// Migrate runs the migrate subcommand: "up" (the default) applies pending
+// migrations, "down [n]" reverts the newest n (default 1) and "version"
+// prints the current schema version.
+func Migrate(ctx context.Context, svc migrations.MigrationService, args []string, out io.Writer) error {
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	switch cmd {
+	case "up":
+		if err := svc.Up(ctx); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("migrate down: invalid step count %q", args[1])
+			}
+			steps = n
+		}
+
+		if err := svc.Down(ctx, steps); err != nil {
+			return err
+		}
+	case "version":
+	default:
+		return fmt.Errorf("migrate: unknown command %q (want up, down [n] or version)", cmd)
+	}
+
+	version, err := svc.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "schema version %d (latest %d)\n", version, svc.Latest())
+	return nil
 }
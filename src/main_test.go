@@ -1,10 +1,232 @@
 package main
 
 import (
-	"database/sql"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/silastgoes/mock-store/src/config"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/metrics"
+	"github.com/silastgoes/mock-store/src/middleware"
+	"github.com/silastgoes/mock-store/src/migrations/mocks"
+	"github.com/silastgoes/mock-store/src/model/apikey"
+	"github.com/silastgoes/mock-store/src/model/product"
+	"github.com/silastgoes/mock-store/src/model/user"
+	"github.com/silastgoes/mock-store/src/money"
+	"github.com/silastgoes/mock-store/src/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
+// newSessions returns sessions kept in users, signed with a fixed key.
+func newSessions(users user.UserModelService) *middleware.Sessions {
+	return middleware.NewSessions(users, []byte(strings.Repeat("k", 32)), time.Hour, logging.Discard())
+}
+
 func TestLoadControllers(t *testing.T) {
-	LoadControlles(&sql.DB{})
+	assert := assert.New(t)
+	srv := product.NewMemoryProductModelService()
+	users := user.NewMemoryUserModelService()
+	assert.Nil(addAdmin(context.Background(), users, config.Auth{AdminUser: "ana", AdminPassword: "correct horse"}, logging.Discard()))
+	m := metrics.New(logging.Discard())
+	m.WatchInventory(srv)
+	handler := LoadControlles(srv, users, apikey.NewMemoryAPIKeyModelService(), newSessions(users), nil, nil, nil, m, logging.Discard())
+
+	cookies := map[string]*http.Cookie{}
+	send := func(method, target, form string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(form))
+		if form != "" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		for _, c := range w.Result().Cookies() {
+			cookies[c.Name] = c
+		}
+		return w
+	}
+
+	w := send(http.MethodGet, "/products/new", "")
+	assert.Equal(http.StatusSeeOther, w.Code)
+	assert.Equal("/login?next=%2Fproducts%2Fnew", w.Header().Get("Location"))
+
+	w = send(http.MethodPost, "/login", "username=ana&password=correct+horse")
+	assert.Equal(http.StatusForbidden, w.Code)
+
+	w = send(http.MethodGet, "/login?next=%2Fproducts%2Fnew", "")
+	token := cookies[middleware.CSRFCookie].Value
+	assert.Contains(w.Body.String(), `name="csrf_token" value="`+token+`"`)
+	assert.Contains(w.Body.String(), `name="next" value="/products/new"`)
+
+	w = send(http.MethodPost, "/login", "username=ana&password=wrong&csrf_token="+token)
+	assert.Equal(http.StatusUnauthorized, w.Code)
+	assert.Contains(w.Body.String(), "Wrong username or password.")
+
+	w = send(http.MethodPost, "/login", "username=ana&password=correct+horse&next=%2Fproducts%2Fnew&csrf_token="+token)
+	assert.Equal(http.StatusSeeOther, w.Code)
+	assert.Equal("/products/new", w.Header().Get("Location"))
+
+	w = send(http.MethodPost, "/products", "name=pen&value=2.50&currency=BRL&quantity=3&csrf_token="+token)
+	assert.Equal(http.StatusForbidden, w.Code, "signing in replaces the CSRF token")
+	token = cookies[middleware.CSRFCookie].Value
+
+	w = send(http.MethodGet, "/products/new", "")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Contains(w.Body.String(), `<span class="navbar-text mr-2">ana</span>`)
+
+	w = send(http.MethodPost, "/products", "name=pen&value=2.50&currency=BRL&quantity=3&csrf_token="+token)
+	assert.Equal(http.StatusSeeOther, w.Code)
+
+	w = send(http.MethodGet, "/products/1/edit", "")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Contains(w.Body.String(), `value="pen"`)
+
+	w = send(http.MethodPost, "/products/1", "_method=PUT&name=pencil&value=1&currency=BRL&quantity=3&csrf_token="+token)
+	assert.Equal(http.StatusSeeOther, w.Code)
+
+	w = send(http.MethodGet, "/", "")
+	assert.Contains(w.Body.String(), "<td>pencil</td>")
+
+	w = send(http.MethodGet, "/products/1", "")
+	assert.Equal(http.StatusMethodNotAllowed, w.Code)
+
+	w = send(http.MethodPost, "/products/1", "_method=DELETE&csrf_token="+token)
+	assert.Equal(http.StatusSeeOther, w.Code)
+
+	w = send(http.MethodGet, "/products/1/edit", "")
+	assert.Equal(http.StatusNotFound, w.Code)
+
+	w = send(http.MethodGet, "/delete?id=1", "")
+	assert.Equal(http.StatusNotFound, w.Code)
+
+	w = send(http.MethodGet, "/readyz", "")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.JSONEq(`{"status":"ready","checks":{"database":"skipped","migrations":"skipped","templates":"ok"}}`, w.Body.String())
+
+	w = send(http.MethodGet, "/metrics", "")
+	assert.Contains(w.Body.String(), `mockstore_http_requests_total{code="303",method="post",route="POST /products"} 1`)
+	assert.Contains(w.Body.String(), `mockstore_db_query_duration_seconds_count{method="Create",outcome="ok"} 1`)
+	assert.Contains(w.Body.String(), "mockstore_products 0\n")
+
+	w = send(http.MethodPost, "/logout", "csrf_token="+token)
+	assert.Equal(http.StatusSeeOther, w.Code)
+	w = send(http.MethodGet, "/", "")
+	assert.Equal(http.StatusSeeOther, w.Code)
+}
+
+func TestTracing(t *testing.T) {
+	assert := assert.New(t)
+
+	prev := otel.GetTracerProvider()
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	defer otel.SetTracerProvider(prev)
+
+	srv := product.NewMemoryProductModelService()
+	srv.Create(context.Background(), "pen", "blue", money.New(250, "BRL"), 3)
+	users := user.NewMemoryUserModelService()
+	id, err := users.Create(context.Background(), "ana", "correct horse", user.Admin)
+	assert.Nil(err)
+	sessions := newSessions(users)
+	handler := LoadControlles(tracing.Products(srv, ""), users, apikey.NewMemoryAPIKeyModelService(), sessions, nil, nil, nil, metrics.New(logging.Discard()), logging.Discard())
+
+	login := httptest.NewRecorder()
+	assert.Nil(sessions.Start(login, httptest.NewRequest(http.MethodPost, "/login", nil), user.User{Id: id, Username: "ana"}))
+
+	req := httptest.NewRequest(http.MethodGet, "/products/1/edit", nil)
+	req.AddCookie(login.Result().Cookies()[0])
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	names := []string{}
+	for _, span := range spans {
+		names = append(names, span.Name())
+		assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	}
+	assert.Equal([]string{"product.Get", "template.Edit", "GET /products/{id}/edit"}, names)
+
+	server := spans[2].SpanContext().SpanID()
+	assert.Equal(server, spans[0].Parent().SpanID())
+	assert.Equal(server, spans[1].Parent().SpanID())
+}
+
+func TestMigrate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	t.Run("Testing up", func(t *testing.T) {
+		svc := mocks.NewMockMigrationService(ctrl)
+		out := &bytes.Buffer{}
+
+		svc.EXPECT().Up(ctx).Return(nil)
+		svc.EXPECT().Version(ctx).Return(3, nil)
+		svc.EXPECT().Latest().Return(3)
+
+		assert.Nil(Migrate(ctx, svc, nil, out))
+		assert.Equal("schema version 3 (latest 3)\n", out.String())
+	})
+
+	t.Run("Testing down", func(t *testing.T) {
+		svc := mocks.NewMockMigrationService(ctrl)
+
+		svc.EXPECT().Down(ctx, 2).Return(nil)
+		svc.EXPECT().Version(ctx).Return(1, nil)
+		svc.EXPECT().Latest().Return(3)
+
+		assert.Nil(Migrate(ctx, svc, []string{"down", "2"}, io.Discard))
+	})
+
+	t.Run("Testing version", func(t *testing.T) {
+		svc := mocks.NewMockMigrationService(ctrl)
+
+		svc.EXPECT().Version(ctx).Return(0, errors.New("boom"))
+
+		assert.Error(Migrate(ctx, svc, []string{"version"}, io.Discard))
+	})
+
+	t.Run("Testing invalid arguments", func(t *testing.T) {
+		svc := mocks.NewMockMigrationService(ctrl)
+
+		assert.Error(Migrate(ctx, svc, []string{"down", "zero"}, io.Discard))
+		assert.Error(Migrate(ctx, svc, []string{"sideways"}, io.Discard))
+	})
+}
+
+func TestAddUser(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	users := user.NewMemoryUserModelService()
+	out := &bytes.Buffer{}
+
+	assert.Nil(AddUser(ctx, users, []string{"Ana"}, strings.NewReader("correct horse\n"), out))
+	assert.Equal("created viewer ana (id 1)\n", out.String())
+
+	u, err := users.Authenticate(ctx, "ana", "correct horse")
+	assert.Nil(err)
+	assert.Equal(1, u.Id)
+	assert.Equal(user.Viewer, u.Role)
+
+	out.Reset()
+	assert.Nil(AddUser(ctx, users, []string{"-role", "Clerk", "cris"}, strings.NewReader("correct horse\n"), out))
+	assert.Equal("created clerk cris (id 2)\n", out.String())
+
+	assert.ErrorIs(AddUser(ctx, users, []string{"ana"}, strings.NewReader("correct horse"), io.Discard), user.ErrConflict)
+	assert.ErrorIs(AddUser(ctx, users, []string{"bob"}, strings.NewReader("short\n"), io.Discard), user.ErrInvalid)
+	assert.ErrorIs(AddUser(ctx, users, []string{"-role", "owner", "bob"}, strings.NewReader("correct horse"), io.Discard), user.ErrInvalid)
+	assert.Error(AddUser(ctx, users, nil, strings.NewReader(""), io.Discard))
 }
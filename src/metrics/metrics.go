@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/silastgoes/mock-store/src/model/product"
+)
+
+const namespace = "mockstore"
+
+// inventoryTimeout bounds the catalogue query run on every scrape.
+var inventoryTimeout = 2 * time.Second
+
+// Metrics holds the collectors exported on /metrics. Each app gets its own
+// registry, so tests can build as many as they like.
+type Metrics struct {
+	Registry *prometheus.Registry
+	logger   *slog.Logger
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	queries  *prometheus.HistogramVec
+}
+
+func New(logger *slog.Logger) *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		logger:   logger,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests answered, by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to answer HTTP requests, by route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "code"}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Time taken by product store calls, by method and outcome.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"method", "outcome"}),
+	}
+
+	m.Registry.MustRegister(
+		m.requests,
+		m.latency,
+		m.queries,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler serves the registry in the Prometheus text format. A collector
+// that fails, such as the inventory when the database is down, is logged and
+// left out rather than failing the whole scrape.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{
+		ErrorLog:      slog.NewLogLogger(m.logger.Handler(), slog.LevelWarn),
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// Instrument counts and times the requests h answers under route, the
+// pattern it was registered with.
+func (m *Metrics) Instrument(route string, h http.Handler) http.Handler {
+	labels := prometheus.Labels{"route": route}
+
+	return promhttp.InstrumentHandlerCounter(m.requests.MustCurryWith(labels),
+		promhttp.InstrumentHandlerDuration(m.latency.MustCurryWith(labels), h))
+}
+
+// WatchDB exports the connection pool's sql.DBStats as gauges and counters.
+func (m *Metrics) WatchDB(db *sql.DB, name string) {
+	m.Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// WatchInventory exports the number of products and the units in stock,
+// read from svc on every scrape.
+func (m *Metrics) WatchInventory(svc product.ProductModelService) {
+	m.Registry.MustRegister(&inventoryCollector{
+		svc: svc,
+		products: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "products"),
+			"Products in the catalogue.", nil, nil),
+		units: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "inventory_units"),
+			"Units in stock across every product.", nil, nil),
+	})
+}
+
+type inventoryCollector struct {
+	svc      product.ProductModelService
+	products *prometheus.Desc
+	units    *prometheus.Desc
+}
+
+func (c *inventoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.products
+	ch <- c.units
+}
+
+func (c *inventoryCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), inventoryTimeout)
+	defer cancel()
+
+	inv, err := c.svc.Inventory(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.products, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.products, prometheus.GaugeValue, float64(inv.Products))
+	ch <- prometheus.MustNewConstMetric(c.units, prometheus.GaugeValue, float64(inv.Units))
+}
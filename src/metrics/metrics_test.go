@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/model/product"
+	"github.com/silastgoes/mock-store/src/model/product/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	return w.Body.String()
+}
+
+func TestInstrument(t *testing.T) {
+	assert := assert.New(t)
+	m := New(logging.Discard())
+
+	h := m.Instrument("GET /products/{id}/edit", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/products/2/edit" {
+			http.NotFound(w, r)
+		}
+	}))
+	for _, target := range []string{"/products/1/edit", "/products/1/edit", "/products/2/edit"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	assert.Equal(2.0, testutil.ToFloat64(m.requests.WithLabelValues("GET /products/{id}/edit", "get", "200")))
+	assert.Equal(1.0, testutil.ToFloat64(m.requests.WithLabelValues("GET /products/{id}/edit", "get", "404")))
+
+	body := scrape(t, m)
+	assert.Contains(body, `mockstore_http_request_duration_seconds_count{code="200",method="get",route="GET /products/{id}/edit"} 2`)
+	assert.Contains(body, "go_goroutines ")
+}
+
+func TestInstrumentProducts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+	m := New(logging.Discard())
+
+	store := mocks.NewMockProductModelService(ctrl)
+	store.EXPECT().Get(gomock.Any(), "1").Return(product.Product{Id: 1}, nil)
+	store.EXPECT().Get(gomock.Any(), "2").Return(product.Product{}, product.ErrNotFound)
+	store.EXPECT().Delete(gomock.Any(), "3").Return(errors.New("connection reset"))
+
+	svc := m.InstrumentProducts(store)
+	p, err := svc.Get(context.Background(), "1")
+	assert.Nil(err)
+	assert.Equal(1, p.Id)
+	_, err = svc.Get(context.Background(), "2")
+	assert.ErrorIs(err, product.ErrNotFound)
+	assert.EqualError(svc.Delete(context.Background(), "3"), "connection reset")
+
+	body := scrape(t, m)
+	assert.Contains(body, `mockstore_db_query_duration_seconds_count{method="Get",outcome="ok"} 2`)
+	assert.Contains(body, `mockstore_db_query_duration_seconds_count{method="Delete",outcome="error"} 1`)
+}
+
+func TestWatchInventory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	t.Run("Testing gauges", func(t *testing.T) {
+		m := New(logging.Discard())
+		store := mocks.NewMockProductModelService(ctrl)
+		store.EXPECT().Inventory(gomock.Any()).Return(product.Inventory{Products: 3, Units: 42}, nil)
+		m.WatchInventory(store)
+
+		body := scrape(t, m)
+		assert.Contains(body, "mockstore_products 3\n")
+		assert.Contains(body, "mockstore_inventory_units 42\n")
+	})
+
+	t.Run("Testing failure leaves the rest of the scrape", func(t *testing.T) {
+		m := New(logging.Discard())
+		store := mocks.NewMockProductModelService(ctrl)
+		store.EXPECT().Inventory(gomock.Any()).Return(product.Inventory{}, errors.New("connection refused"))
+		m.WatchInventory(store)
+
+		body := scrape(t, m)
+		assert.NotContains(body, "mockstore_products")
+		assert.Contains(body, "go_goroutines ")
+	})
+}
+
+func TestWatchDB(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.Nil(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(7)
+
+	m := New(logging.Discard())
+	m.WatchDB(db, "lojamock")
+
+	body := scrape(t, m)
+	assert.True(t, strings.Contains(body, `go_sql_max_open_connections{db_name="lojamock"} 7`), body)
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/silastgoes/mock-store/src/model/product"
+	"github.com/silastgoes/mock-store/src/money"
+)
+
+// instrumentedProducts times every call to the wrapped store.
+type instrumentedProducts struct {
+	next    product.ProductModelService
+	queries *prometheus.HistogramVec
+}
+
+// InstrumentProducts wraps svc so the latency of each of its methods is
+// recorded in mockstore_db_query_duration_seconds.
+func (m *Metrics) InstrumentProducts(svc product.ProductModelService) product.ProductModelService {
+	return &instrumentedProducts{next: svc, queries: m.queries}
+}
+
+// observe records the time since start under method, deferred so err
+// points at the call's final error. Missing rows and rejected input are
+// answers, not failures, so only other errors count as "error".
+func (ip *instrumentedProducts) observe(method string, start time.Time, err *error) {
+	outcome := "ok"
+	if *err != nil && !errors.Is(*err, product.ErrNotFound) && !errors.Is(*err, product.ErrInvalid) {
+		outcome = "error"
+	}
+
+	ip.queries.WithLabelValues(method, outcome).Observe(time.Since(start).Seconds())
+}
+
+func (ip *instrumentedProducts) Create(ctx context.Context, name, description string, value money.Money, quantity int) (id int, err error) {
+	defer ip.observe("Create", time.Now(), &err)
+	return ip.next.Create(ctx, name, description, value, quantity)
+}
+
+func (ip *instrumentedProducts) Get(ctx context.Context, param string) (p product.Product, err error) {
+	defer ip.observe("Get", time.Now(), &err)
+	return ip.next.Get(ctx, param)
+}
+
+func (ip *instrumentedProducts) ListProducts(ctx context.Context, opts product.ListOptions) (page product.Page, err error) {
+	defer ip.observe("ListProducts", time.Now(), &err)
+	return ip.next.ListProducts(ctx, opts)
+}
+
+func (ip *instrumentedProducts) Update(ctx context.Context, id int, name, description string, value money.Money, quantity int) (err error) {
+	defer ip.observe("Update", time.Now(), &err)
+	return ip.next.Update(ctx, id, name, description, value, quantity)
+}
+
+func (ip *instrumentedProducts) SetQuantity(ctx context.Context, id int, quantity int) (err error) {
+	defer ip.observe("SetQuantity", time.Now(), &err)
+	return ip.next.SetQuantity(ctx, id, quantity)
+}
+
+func (ip *instrumentedProducts) Delete(ctx context.Context, id string) (err error) {
+	defer ip.observe("Delete", time.Now(), &err)
+	return ip.next.Delete(ctx, id)
+}
+
+func (ip *instrumentedProducts) Inventory(ctx context.Context) (inv product.Inventory, err error) {
+	defer ip.observe("Inventory", time.Now(), &err)
+	return ip.next.Inventory(ctx)
+}
+
+func (ip *instrumentedProducts) History(ctx context.Context, id string) (entries []product.AuditEntry, err error) {
+	defer ip.observe("History", time.Now(), &err)
+	return ip.next.History(ctx, id)
+}
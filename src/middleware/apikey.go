@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/model/apikey"
+)
+
+// APIPrefix starts the path of every JSON endpoint APIKeys guards.
+const APIPrefix = "/api/"
+
+type apiKeyKey struct{}
+
+// APIKeys guards the JSON API. Every request under /api/ must carry a live
+// key as "Authorization: Bearer <token>", answered with 401 otherwise, and
+// a key with the read scope gets 403 for anything but reads. Other paths
+// pass untouched.
+func APIKeys(keys apikey.APIKeyModelService, logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, APIPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := bearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="mock-store"`)
+				writeAPIError(w, http.StatusUnauthorized, "an API key is required in the Authorization: Bearer header")
+				return
+			}
+
+			key, err := keys.Authenticate(r.Context(), token)
+			if errors.Is(err, apikey.ErrUnknown) {
+				logger.WarnContext(r.Context(), logging.APIKeyRejected, "reason", "unknown")
+				w.Header().Set("WWW-Authenticate", `Bearer realm="mock-store", error="invalid_token"`)
+				writeAPIError(w, http.StatusUnauthorized, "the API key is unknown, revoked or expired")
+				return
+			}
+			if err != nil {
+				logger.ErrorContext(r.Context(), logging.APIKeyFailed, "error", err)
+				writeAPIError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+				return
+			}
+
+			ctx := logging.With(r.Context(), slog.String("api_key", key.Prefix))
+			if !key.Scope.Allows(r.Method) {
+				logger.WarnContext(ctx, logging.APIKeyRejected, "reason", "scope", "scope", key.Scope, "method", r.Method)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="mock-store", error="insufficient_scope", scope="write"`)
+				writeAPIError(w, http.StatusForbidden, "the API key is read-only")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, apiKeyKey{}, key)))
+		})
+	}
+}
+
+// CurrentAPIKey returns the key APIKeys accepted for this request.
+func CurrentAPIKey(ctx context.Context) (apikey.APIKey, bool) {
+	k, ok := ctx.Value(apiKeyKey{}).(apikey.APIKey)
+	return k, ok
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// writeAPIError answers in the same {"error": {...}} shape as the API
+// controllers.
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{"status": status, "message": message},
+	})
+}
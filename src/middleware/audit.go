@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/silastgoes/mock-store/src/model/product"
+)
+
+// Audit names who is behind the request in its context, for the product
+// audit log: the signed-in user, or else the API key, and the request id.
+// It must run after Sessions.Load and APIKeys.
+func Audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := product.Actor{Name: "anonymous", RequestId: RequestIDFrom(r.Context())}
+		if u, ok := CurrentUser(r.Context()); ok {
+			actor.Name = "user:" + u.Username
+		} else if k, ok := CurrentAPIKey(r.Context()); ok {
+			actor.Name = "api_key:" + k.Prefix
+		}
+
+		next.ServeHTTP(w, r.WithContext(product.WithActor(r.Context(), actor)))
+	})
+}
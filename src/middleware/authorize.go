@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/model/user"
+)
+
+// Authorize lets through signed-in users whose role is allowed p. Anyone
+// not signed in is sent to log in, as by RequireLogin; anyone else gets 403.
+func Authorize(p user.Permission, logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return RequireLogin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u, _ := CurrentUser(r.Context())
+			if !u.Role.Can(p) {
+				Forbid(w, r, logger, p)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// Forbid answers 403 to a signed-in user whose role lacks p.
+func Forbid(w http.ResponseWriter, r *http.Request, logger *slog.Logger, p user.Permission) {
+	u, _ := CurrentUser(r.Context())
+	logger.WarnContext(r.Context(), logging.AccessDenied, "permission", p, "role", u.Role)
+	http.Error(w, "your role does not allow this", http.StatusForbidden)
+}
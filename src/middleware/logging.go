@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/logging"
+)
+
+// AccessLog logs one record per request once it has been answered: method,
+// path, status, bytes written and latency. The query is left out, as it can
+// carry an OIDC code or a next= target.
+func AccessLog(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := record(w)
+
+			next.ServeHTTP(rec, r)
+
+			logger.InfoContext(r.Context(), logging.HttpRequest,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", rec.Status(),
+				"bytes", rec.bytes,
+				"duration", time.Since(start),
+			)
+		})
+	}
+}
+
+var errorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Internal Server Error</title></head>
+<body>
+    <h1>Something went wrong</h1>
+    <p>The request could not be completed.{{with .}} Please quote request id <code>{{.}}</code> when reporting it.{{end}}</p>
+    <p><a href="/">Back</a></p>
+</body>
+</html>
+`))
+
+// Recover turns a panicking handler into a logged stack trace and a 500
+// page, as long as the handler had not started its response yet.
+func Recover(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := record(w)
+
+			defer func() {
+				err := recover()
+				if err == nil {
+					return
+				}
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				logger.ErrorContext(r.Context(), logging.HttpPanic,
+					"error", err,
+					"stack", string(debug.Stack()),
+				)
+				if rec.written() {
+					return
+				}
+
+				rec.Header().Set("Content-Type", "text/html; charset=utf-8")
+				rec.WriteHeader(http.StatusInternalServerError)
+				errorPage.Execute(rec, RequestIDFrom(r.Context()))
+			}()
+
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
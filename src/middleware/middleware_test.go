@@ -0,0 +1,466 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/model/user"
+	"github.com/silastgoes/mock-store/src/model/user/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// captureLog returns a text logger writing to the returned buffer.
+func captureLog(t *testing.T) (*slog.Logger, *bytes.Buffer) {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	logger, err := logging.New(buf, "text", "info")
+	assert.Nil(t, err)
+
+	return logger, buf
+}
+
+func TestChain(t *testing.T) {
+	order := []string{}
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	})
+
+	Chain(h, mark("outer"), mark("inner")).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, []string{"outer", "inner", "handler"}, order)
+}
+
+func TestRequestID(t *testing.T) {
+	assert := assert.New(t)
+
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFrom(r.Context())
+	}))
+
+	t.Run("Testing generated id", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Len(seen, 32)
+		assert.Equal(seen, w.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("Testing propagated id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, "abc-123")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal("abc-123", seen)
+		assert.Equal("abc-123", w.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("Testing unsafe id is replaced", func(t *testing.T) {
+		for _, id := range []string{"has space", "line\nbreak", strings.Repeat("a", 129)} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(RequestIDHeader, id)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.NotEqual(id, seen)
+			assert.Len(seen, 32)
+		}
+	})
+}
+
+func TestAccessLog(t *testing.T) {
+	logger, buf := captureLog(t)
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "inside")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}), RequestID, AccessLog(logger))
+
+	req := httptest.NewRequest(http.MethodPost, "/products?code=secret", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Regexp(t, `level=INFO msg=inside request_id=req-1$`, lines[0])
+	assert.Regexp(t, `level=INFO msg=http.request method=POST path=/products status=201 bytes=5 duration=\S+ request_id=req-1$`, lines[1])
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestRecover(t *testing.T) {
+	assert := assert.New(t)
+	logger, buf := captureLog(t)
+
+	t.Run("Testing panic before the response", func(t *testing.T) {
+		h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("template exploded")
+		}), RequestID, AccessLog(logger), Recover(logger))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, "req-2")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(http.StatusInternalServerError, w.Code)
+		assert.Contains(w.Body.String(), "<code>req-2</code>")
+		assert.Contains(buf.String(), `level=ERROR msg=http.panic error="template exploded" stack=`)
+		assert.Contains(buf.String(), "status=500")
+		assert.Contains(buf.String(), "request_id=req-2")
+	})
+
+	t.Run("Testing panic after the response started", func(t *testing.T) {
+		h := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("partial"))
+			panic("too late")
+		}))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal("partial", w.Body.String())
+	})
+
+	t.Run("Testing aborted handler", func(t *testing.T) {
+		h := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+
+		assert.PanicsWithValue(http.ErrAbortHandler, func() {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	})
+}
+
+func TestTrace(t *testing.T) {
+	assert := assert.New(t)
+
+	prev := otel.GetTracerProvider()
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	defer otel.SetTracerProvider(prev)
+
+	logger, buf := captureLog(t)
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		NameSpan(r, "GET /products/{id}/edit")
+		logger.InfoContext(r.Context(), "inside")
+		w.WriteHeader(http.StatusBadGateway)
+	}), RequestID, Trace)
+
+	req := httptest.NewRequest(http.MethodGet, "/products/1/edit", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(RequestIDHeader, "req-3")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	assert.Len(spans, 1)
+	span := spans[0]
+	assert.Equal("GET /products/{id}/edit", span.Name())
+	assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal("00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.True(span.Parent().IsRemote())
+	assert.Contains(span.Attributes(), attribute.String("http.route", "GET /products/{id}/edit"))
+	assert.Contains(span.Attributes(), attribute.Int("http.response.status_code", 502))
+	assert.Contains(span.Attributes(), attribute.String("request_id", "req-3"))
+	assert.Equal(codes.Error, span.Status().Code)
+	assert.Contains(buf.String(), "msg=inside request_id=req-3 trace_id=4bf92f3577b34da6a3ce929d0e0e4736")
+}
+
+func TestCSRF(t *testing.T) {
+	assert := assert.New(t)
+	logger, buf := captureLog(t)
+
+	seen := ""
+	handler := CSRF(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = CSRFToken(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := w.Result().Cookies()
+	assert.Len(cookies, 1)
+	token := cookies[0].Value
+	assert.Equal(CSRFCookie, cookies[0].Name)
+	assert.True(cookies[0].HttpOnly)
+	assert.Equal(http.SameSiteLaxMode, cookies[0].SameSite)
+	assert.Equal(token, seen)
+	assert.True(validCSRFToken(token))
+
+	post := func(form, header, cookie string) *httptest.ResponseRecorder {
+		seen = ""
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if header != "" {
+			req.Header.Set(CSRFHeader, header)
+		}
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: CSRFCookie, Value: cookie})
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Testing missing token", func(t *testing.T) {
+		assert.Equal(http.StatusForbidden, post("name=pen", "", token).Code)
+		assert.Equal(http.StatusForbidden, post("csrf_token="+token, "", "").Code)
+		assert.Empty(seen)
+		assert.Contains(buf.String(), "msg="+logging.CSRFRejected)
+	})
+
+	t.Run("Testing mismatched token", func(t *testing.T) {
+		other := newCSRFToken()
+		assert.Equal(http.StatusForbidden, post("csrf_token="+other, "", token).Code)
+		assert.Equal(http.StatusForbidden, post("", other, token).Code)
+		assert.Equal(http.StatusForbidden, post("csrf_token=short", "", "short").Code)
+	})
+
+	t.Run("Testing matching token", func(t *testing.T) {
+		w := post("csrf_token="+token, "", token)
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal(token, seen)
+		assert.Empty(w.Result().Cookies())
+
+		assert.Equal(http.StatusOK, post("", token, token).Code)
+	})
+
+	t.Run("Testing bearer API calls", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/products/1", nil)
+		req.Header.Set("Authorization", "Bearer key")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(http.StatusOK, w.Code)
+
+		req = httptest.NewRequest(http.MethodDelete, "/products/1", nil)
+		req.Header.Set("Authorization", "Bearer key")
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(http.StatusForbidden, w.Code)
+	})
+}
+
+func TestSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+	logger, buf := captureLog(t)
+
+	users := mocks.NewMockUserModelService(ctrl)
+	sessions := NewSessions(users, []byte(strings.Repeat("k", 32)), time.Hour, logger)
+	ana := user.User{Id: 3, Username: "ana"}
+
+	var seen *user.User
+	handler := sessions.Load(RequireLogin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, _ := CurrentUser(r.Context())
+		seen = &u
+		logger.InfoContext(r.Context(), "served")
+	})))
+	get := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		seen = nil
+		req := httptest.NewRequest(http.MethodGet, "/products/new", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	users.EXPECT().CreateSession(gomock.Any(), 3, time.Hour).Return("token", nil)
+	w := httptest.NewRecorder()
+	assert.Nil(sessions.Start(w, httptest.NewRequest(http.MethodPost, "/login", nil), ana))
+	cookie := w.Result().Cookies()[0]
+	assert.Equal(SessionCookie, cookie.Name)
+	assert.True(strings.HasPrefix(cookie.Value, "token."))
+	assert.True(cookie.HttpOnly)
+
+	t.Run("Testing signed in", func(t *testing.T) {
+		users.EXPECT().Session(gomock.Any(), "token").Return(user.Session{User: ana}, nil)
+
+		assert.Equal(http.StatusOK, get(cookie).Code)
+		assert.Equal(&ana, seen)
+		assert.Contains(buf.String(), "msg=served user=ana")
+	})
+
+	t.Run("Testing anonymous", func(t *testing.T) {
+		w := get(nil)
+		assert.Nil(seen)
+		assert.Equal(http.StatusSeeOther, w.Code)
+		assert.Equal("/login?next=%2Fproducts%2Fnew", w.Header().Get("Location"))
+	})
+
+	t.Run("Testing forged cookie", func(t *testing.T) {
+		forged := *cookie
+		forged.Value = "other." + strings.SplitN(cookie.Value, ".", 2)[1]
+
+		assert.Equal(http.StatusSeeOther, get(&forged).Code)
+		assert.Nil(seen)
+	})
+
+	t.Run("Testing ended session", func(t *testing.T) {
+		users.EXPECT().Session(gomock.Any(), "token").Return(user.Session{}, user.ErrNotFound)
+
+		w := get(cookie)
+		assert.Equal(http.StatusSeeOther, w.Code)
+		assert.Equal(-1, w.Result().Cookies()[0].MaxAge)
+	})
+
+	t.Run("Testing store failure", func(t *testing.T) {
+		users.EXPECT().Session(gomock.Any(), "token").Return(user.Session{}, errors.New("boom"))
+
+		w := get(cookie)
+		assert.Equal(http.StatusInternalServerError, w.Code)
+		assert.Nil(seen)
+		assert.Empty(w.Result().Cookies(), "the cookie outlives the outage")
+		assert.Contains(buf.String(), "msg="+logging.SessionFailed)
+	})
+
+	t.Run("Testing logout", func(t *testing.T) {
+		users.EXPECT().DeleteSession(gomock.Any(), "token").Return(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		assert.Nil(sessions.End(w, req))
+		assert.Equal(-1, w.Result().Cookies()[0].MaxAge)
+	})
+}
+
+func TestCSRFRotation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	users := mocks.NewMockUserModelService(ctrl)
+	sessions := NewSessions(users, []byte(strings.Repeat("k", 32)), time.Hour, logging.Discard())
+	handler := CSRF(logging.Discard())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/login":
+			assert.Nil(sessions.Start(w, r, user.User{Id: 3, Username: "ana"}))
+		case r.URL.Path == "/logout":
+			assert.Nil(sessions.End(w, r))
+		}
+	}))
+	csrfCookie := func(w *httptest.ResponseRecorder) string {
+		for _, c := range w.Result().Cookies() {
+			if c.Name == CSRFCookie {
+				return c.Value
+			}
+		}
+		return ""
+	}
+	post := func(path, form, cookie string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: CSRFCookie, Value: cookie})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+	before := csrfCookie(w)
+
+	users.EXPECT().CreateSession(gomock.Any(), 3, time.Hour).Return("token", nil)
+	w = post("/login", "csrf_token="+before, before)
+	assert.Equal(http.StatusOK, w.Code)
+	after := csrfCookie(w)
+	assert.True(validCSRFToken(after))
+	assert.NotEqual(before, after)
+
+	assert.Equal(http.StatusForbidden, post("/products", "csrf_token="+before, after).Code, "a token from before login is rejected")
+	assert.Equal(http.StatusOK, post("/products", "csrf_token="+after, after).Code)
+
+	w = post("/logout", "csrf_token="+after, after)
+	assert.Equal(http.StatusOK, w.Code)
+	out := csrfCookie(w)
+	assert.NotEqual(after, out)
+	assert.Equal(http.StatusForbidden, post("/products", "csrf_token="+after, out).Code, "a token from before logout is rejected")
+}
+
+func TestSignedCookies(t *testing.T) {
+	assert := assert.New(t)
+	sessions := NewSessions(nil, []byte(strings.Repeat("k", 32)), time.Hour, logging.Discard())
+
+	w := httptest.NewRecorder()
+	sessions.SetSigned(w, httptest.NewRequest(http.MethodGet, "/login/oidc", nil), "flow", "/login/oidc", "a.b", 10*time.Minute)
+	cookie := w.Result().Cookies()[0]
+	assert.Equal("/login/oidc", cookie.Path)
+	assert.Equal(600, cookie.MaxAge)
+	assert.True(cookie.HttpOnly)
+
+	read := func(c *http.Cookie) (string, bool) {
+		req := httptest.NewRequest(http.MethodGet, "/login/oidc/callback", nil)
+		req.AddCookie(c)
+		return sessions.Signed(req, "flow")
+	}
+
+	value, ok := read(cookie)
+	assert.True(ok)
+	assert.Equal("a.b", value)
+
+	forged := *cookie
+	forged.Value = "a.c" + strings.TrimPrefix(cookie.Value, "a.b")
+	_, ok = read(&forged)
+	assert.False(ok)
+
+	renamed := *cookie
+	renamed.Name = "other"
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&renamed)
+	_, ok = sessions.Signed(req, "other")
+	assert.False(ok, "the signature covers the name")
+
+	w = httptest.NewRecorder()
+	sessions.ClearSigned(w, req, "flow", "/login/oidc")
+	assert.Equal(-1, w.Result().Cookies()[0].MaxAge)
+}
+
+func TestAuthorize(t *testing.T) {
+	assert := assert.New(t)
+	logger, buf := captureLog(t)
+
+	handler := Authorize(user.DeleteProducts, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serve := func(u *user.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/products/1", nil)
+		if u != nil {
+			req = req.WithContext(WithUser(req.Context(), *u))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(http.StatusNoContent, serve(&user.User{Username: "ana", Role: user.Manager}).Code)
+
+	w := serve(&user.User{Username: "cris", Role: user.Clerk})
+	assert.Equal(http.StatusForbidden, w.Code)
+	assert.Contains(buf.String(), "msg="+logging.AccessDenied+" permission=products:delete role=clerk user=cris")
+
+	w = serve(nil)
+	assert.Equal(http.StatusSeeOther, w.Code)
+	assert.Equal(LoginPath, w.Header().Get("Location"))
+}
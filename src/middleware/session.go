@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/model/user"
+)
+
+// SessionCookie holds the signed session token of a signed-in browser.
+const SessionCookie = "session"
+
+// LoginPath is where RequireLogin sends visitors who are not signed in.
+const LoginPath = "/login"
+
+type userKey struct{}
+
+// Sessions keeps browsers signed in. The cookie carries a random token
+// signed with key; the session it names, and its expiry, live in the user
+// store, so signing out or expiring ends it on the server too.
+type Sessions struct {
+	users  user.UserModelService
+	key    []byte
+	ttl    time.Duration
+	logger *slog.Logger
+}
+
+func NewSessions(users user.UserModelService, key []byte, ttl time.Duration, logger *slog.Logger) *Sessions {
+	return &Sessions{
+		users:  users,
+		key:    key,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// Load puts the user signed in with the request's session cookie in the
+// request context. A cookie with a bad signature or naming a session that
+// has ended is cleared and the request goes on anonymously. When the store
+// cannot be asked, the request fails but the cookie is kept, so an outage
+// does not sign everyone out.
+func (s *Sessions) Load(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := s.token(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sess, err := s.users.Session(r.Context(), token)
+		if errors.Is(err, user.ErrNotFound) {
+			s.clear(w, r)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), logging.SessionFailed, "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), sess.User)))
+	})
+}
+
+// Start signs u in: it ends the session the request came with, if any, and
+// sets a cookie for a fresh one along with a new CSRF token.
+func (s *Sessions) Start(w http.ResponseWriter, r *http.Request, u user.User) error {
+	if old, ok := s.token(r); ok {
+		if err := s.users.DeleteSession(r.Context(), old); err != nil {
+			return err
+		}
+	}
+
+	token, err := s.users.CreateSession(r.Context(), u.Id, s.ttl)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token + "." + s.sign(token),
+		Path:     "/",
+		Expires:  time.Now().Add(s.ttl),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	rotateCSRFToken(w, r)
+
+	return nil
+}
+
+// End signs the request's session out, clears its cookie and replaces the
+// CSRF token.
+func (s *Sessions) End(w http.ResponseWriter, r *http.Request) error {
+	s.clear(w, r)
+	rotateCSRFToken(w, r)
+
+	token, ok := s.token(r)
+	if !ok {
+		return nil
+	}
+
+	return s.users.DeleteSession(r.Context(), token)
+}
+
+// SetSigned stores value in cookie name under path for ttl, signed with the
+// session key so Signed can tell it was set here. The signature covers the
+// name, so one cookie cannot stand in for another.
+func (s *Sessions) SetSigned(w http.ResponseWriter, r *http.Request, name, path, value string, ttl time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value + "." + s.sign(name+"="+value),
+		Path:     path,
+		MaxAge:   int(ttl / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Signed returns the value SetSigned stored in cookie name, when its
+// signature checks out.
+func (s *Sessions) Signed(r *http.Request, name string) (string, bool) {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return "", false
+	}
+
+	i := strings.LastIndex(c.Value, ".")
+	if i < 0 {
+		return "", false
+	}
+	value, mac := c.Value[:i], c.Value[i+1:]
+	if !hmac.Equal([]byte(mac), []byte(s.sign(name+"="+value))) {
+		return "", false
+	}
+
+	return value, true
+}
+
+// ClearSigned removes cookie name set under path.
+func (s *Sessions) ClearSigned(w http.ResponseWriter, r *http.Request, name, path string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     path,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// WithUser stores u in ctx, both for CurrentUser and as the user of every
+// record logged with ctx.
+func WithUser(ctx context.Context, u user.User) context.Context {
+	ctx = logging.With(ctx, slog.String("user", u.Username))
+	return context.WithValue(ctx, userKey{}, u)
+}
+
+// CurrentUser returns the user Load found signed in for this request.
+func CurrentUser(ctx context.Context) (user.User, bool) {
+	u, ok := ctx.Value(userKey{}).(user.User)
+	return u, ok
+}
+
+// RequireLogin lets signed-in requests through and sends everyone else to
+// the login page, which brings them back here afterwards.
+func RequireLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := CurrentUser(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		target := LoginPath
+		if r.Method == http.MethodGet {
+			target += "?next=" + url.QueryEscape(r.URL.RequestURI())
+		}
+
+		http.Redirect(w, r, target, http.StatusSeeOther)
+	})
+}
+
+// token returns the session token of the request's cookie when its
+// signature checks out.
+func (s *Sessions) token(r *http.Request) (string, bool) {
+	c, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return "", false
+	}
+
+	token, mac, ok := strings.Cut(c.Value, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(s.sign(token))) {
+		return "", false
+	}
+
+	return token, true
+}
+
+func (s *Sessions) sign(token string) string {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func (s *Sessions) clear(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,198 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+}
+
+//go:generate mockgen --source=migrations.go --package=mocks --destination=./mocks/migrations.go  MigrationService
+type MigrationService interface {
+	Up(ctx context.Context) error
+	Down(ctx context.Context, steps int) error
+	Version(ctx context.Context) (int, error)
+	Latest() int
+}
+
+func NewMigrationService(db *sql.DB) *migrator {
+	migrations, err := Load(embedded)
+	if err != nil {
+		panic(err)
+	}
+
+	return &migrator{
+		DB:         db,
+		Migrations: migrations,
+	}
+}
+
+// Load reads every sql/NNNN_name.{up,down}.sql pair from fsys, sorted by
+// version. A version without both halves is an error.
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, file := range files {
+		parts := fileName.FindStringSubmatch(path.Base(file))
+		if parts == nil {
+			return nil, fmt.Errorf("migrations: malformed file name %q", file)
+		}
+
+		version, _ := strconv.Atoi(parts[1])
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = m
+		}
+		if m.Name != parts[2] {
+			return nil, fmt.Errorf("migrations: version %d has two names, %q and %q", version, m.Name, parts[2])
+		}
+
+		body, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		if parts[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migrations: version %d is missing its up or down file", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every migration newer than the current version, each one in its
+// own transaction together with its schema_migrations row.
+func (mig *migrator) Up(ctx context.Context) error {
+	if err := mig.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	current, err := mig.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range mig.Migrations {
+		if m.Version <= current {
+			continue
+		}
+
+		err = mig.apply(ctx, m.Up, "INSERT INTO schema_migrations(version, name) VALUES($1, $2)", m.Version, m.Name)
+		if err != nil {
+			return fmt.Errorf("migrations: applying %04d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Down reverts the newest steps migrations, newest first.
+func (mig *migrator) Down(ctx context.Context, steps int) error {
+	if err := mig.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	current, err := mig.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := len(mig.Migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := mig.Migrations[i]
+		if m.Version > current {
+			continue
+		}
+
+		err = mig.apply(ctx, m.Down, "DELETE FROM schema_migrations WHERE version=$1", m.Version)
+		if err != nil {
+			return fmt.Errorf("migrations: reverting %04d_%s: %w", m.Version, m.Name, err)
+		}
+		steps--
+	}
+
+	return nil
+}
+
+func (mig *migrator) Version(ctx context.Context) (int, error) {
+	var version int
+
+	err := mig.DB.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+// Latest is the newest version known to this binary.
+func (mig *migrator) Latest() int {
+	if len(mig.Migrations) == 0 {
+		return 0
+	}
+
+	return mig.Migrations[len(mig.Migrations)-1].Version
+}
+
+func (mig *migrator) ensureTable(ctx context.Context) error {
+	_, err := mig.DB.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    INTEGER PRIMARY KEY,
+	name       TEXT NOT NULL DEFAULT '',
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`)
+	return err
+}
+
+func (mig *migrator) apply(ctx context.Context, script, bookkeeping string, args ...interface{}) error {
+	tx, err := mig.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
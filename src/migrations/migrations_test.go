@@ -0,0 +1,141 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var ctx = context.Background()
+
+func testMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Name: "create_product", Up: "CREATE TABLE product", Down: "DROP TABLE product"},
+		{Version: 2, Name: "add_sku", Up: "ALTER TABLE product ADD sku", Down: "ALTER TABLE product DROP sku"},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("Testing embedded migrations", func(t *testing.T) {
+		migrations, err := Load(embedded)
+
+		assert.Nil(err)
+		assert.NotEmpty(migrations)
+		for i, m := range migrations {
+			assert.NotEmpty(m.Up)
+			assert.NotEmpty(m.Down)
+			if i > 0 {
+				assert.Less(migrations[i-1].Version, m.Version)
+			}
+		}
+	})
+
+	t.Run("Testing sorting", func(t *testing.T) {
+		migrations, err := Load(fstest.MapFS{
+			"sql/0002_add_sku.up.sql":          {Data: []byte("up 2")},
+			"sql/0002_add_sku.down.sql":        {Data: []byte("down 2")},
+			"sql/0001_create_product.up.sql":   {Data: []byte("up 1")},
+			"sql/0001_create_product.down.sql": {Data: []byte("down 1")},
+		})
+
+		assert.Nil(err)
+		assert.Equal([]Migration{
+			{Version: 1, Name: "create_product", Up: "up 1", Down: "down 1"},
+			{Version: 2, Name: "add_sku", Up: "up 2", Down: "down 2"},
+		}, migrations)
+	})
+
+	t.Run("Testing missing down file", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{
+			"sql/0001_create_product.up.sql": {Data: []byte("up 1")},
+		})
+
+		assert.Error(err)
+	})
+
+	t.Run("Testing malformed name", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{
+			"sql/create_product.sql": {Data: []byte("up 1")},
+		})
+
+		assert.Error(err)
+	})
+}
+
+func TestUp(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	defer db.Close()
+	assert.Nil(err)
+
+	mig := NewMigrationService(db)
+	mig.Migrations = testMigrations()
+
+	t.Run("Testing pending migrations", func(t *testing.T) {
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(MAX(version), 0) FROM schema_migrations")).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("ALTER TABLE product ADD sku").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations(version, name) VALUES($1, $2)")).
+			WithArgs(2, "add_sku").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.Nil(mig.Up(ctx))
+		assert.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("Testing failing migration", func(t *testing.T) {
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(MAX(version), 0) FROM schema_migrations")).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(0))
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TABLE product").WillReturnError(errors.New("boom"))
+		mock.ExpectRollback()
+
+		assert.Error(mig.Up(ctx))
+		assert.Nil(mock.ExpectationsWereMet())
+	})
+}
+
+func TestDown(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	defer db.Close()
+	assert.Nil(err)
+
+	mig := NewMigrationService(db)
+	mig.Migrations = testMigrations()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(MAX(version), 0) FROM schema_migrations")).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE product DROP sku").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version=$1")).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.Nil(mig.Down(ctx, 1))
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestLatest(t *testing.T) {
+	assert := assert.New(t)
+
+	mig := NewMigrationService(nil)
+	mig.Migrations = testMigrations()
+	assert.Equal(2, mig.Latest())
+
+	mig.Migrations = nil
+	assert.Equal(0, mig.Latest())
+}
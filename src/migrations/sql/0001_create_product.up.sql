@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS product (
+    id          SERIAL PRIMARY KEY,
+    name        VARCHAR(255)     NOT NULL,
+    description TEXT             NOT NULL DEFAULT '',
+    value       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    quantity    INTEGER          NOT NULL DEFAULT 0
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS product_quantity_id_idx;
+DROP INDEX IF EXISTS product_value_id_idx;
+DROP INDEX IF EXISTS product_name_id_idx;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS product_name_id_idx ON product (name, id);
+CREATE INDEX IF NOT EXISTS product_value_id_idx ON product (value, id);
+CREATE INDEX IF NOT EXISTS product_quantity_id_idx ON product (quantity, id);
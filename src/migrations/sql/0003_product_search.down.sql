@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS product_search_idx;
+ALTER TABLE product DROP COLUMN IF EXISTS search;
@@ -0,0 +1,3 @@
+ALTER TABLE product
+    DROP COLUMN IF EXISTS currency,
+    ALTER COLUMN value TYPE DOUBLE PRECISION USING value::double precision;
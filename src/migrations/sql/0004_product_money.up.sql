@@ -0,0 +1,3 @@
+ALTER TABLE product
+    ALTER COLUMN value TYPE NUMERIC(14, 2) USING round(value::numeric, 2),
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'BRL';
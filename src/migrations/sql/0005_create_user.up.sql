@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS app_user (
+    id            SERIAL PRIMARY KEY,
+    username      VARCHAR(64)  NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS session (
+    id         CHAR(64)    PRIMARY KEY,
+    user_id    INTEGER     NOT NULL REFERENCES app_user (id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS session_expires_at_idx ON session (expires_at);
@@ -0,0 +1,7 @@
+ALTER TABLE app_user
+    ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'admin'
+        CHECK (role IN ('viewer', 'clerk', 'manager', 'admin'));
+
+-- Users created before roles existed could do everything and keep doing so;
+-- new users start as viewers.
+ALTER TABLE app_user ALTER COLUMN role SET DEFAULT 'viewer';
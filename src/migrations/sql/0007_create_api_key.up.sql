@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS api_key (
+    id           SERIAL PRIMARY KEY,
+    name         VARCHAR(64) NOT NULL,
+    prefix       CHAR(8)     NOT NULL,
+    key_hash     CHAR(64)    NOT NULL UNIQUE,
+    scope        VARCHAR(8)  NOT NULL CHECK (scope IN ('read', 'write')),
+    created_by   INTEGER     REFERENCES app_user (id) ON DELETE SET NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ
+);
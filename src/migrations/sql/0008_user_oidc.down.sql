@@ -0,0 +1,7 @@
+DELETE FROM app_user WHERE password_hash IS NULL;
+DROP INDEX IF EXISTS app_user_oidc_idx;
+ALTER TABLE app_user
+    DROP CONSTRAINT IF EXISTS app_user_login_check,
+    DROP COLUMN IF EXISTS oidc_subject,
+    DROP COLUMN IF EXISTS oidc_issuer,
+    ALTER COLUMN password_hash SET NOT NULL;
@@ -0,0 +1,10 @@
+ALTER TABLE app_user
+    ADD COLUMN IF NOT EXISTS oidc_issuer  VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS oidc_subject VARCHAR(255),
+    ALTER COLUMN password_hash DROP NOT NULL;
+
+-- Single sign-on users have no password and are found by who vouches for
+-- them, not by username.
+ALTER TABLE app_user
+    ADD CONSTRAINT app_user_login_check CHECK (password_hash IS NOT NULL OR oidc_subject IS NOT NULL);
+CREATE UNIQUE INDEX IF NOT EXISTS app_user_oidc_idx ON app_user (oidc_issuer, oidc_subject);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS product_audit;
+DROP FUNCTION IF EXISTS product_audit_append_only();
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS product_audit (
+    id         BIGSERIAL   PRIMARY KEY,
+    product_id INTEGER     NOT NULL,
+    action     VARCHAR(8)  NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    actor      TEXT        NOT NULL,
+    before     JSONB,
+    after      JSONB,
+    request_id TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS product_audit_product_id_idx ON product_audit (product_id, id);
+
+CREATE OR REPLACE FUNCTION product_audit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'product_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER product_audit_append_only
+    BEFORE UPDATE OR DELETE ON product_audit
+    FOR EACH ROW EXECUTE FUNCTION product_audit_append_only();
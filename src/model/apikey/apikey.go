@@ -0,0 +1,251 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/logging"
+)
+
+// Scope is what a key may do: read only, or read and write.
+type Scope string
+
+const (
+	Read  Scope = "read"
+	Write Scope = "write"
+)
+
+// Allows reports whether a key with scope s may make a request with method.
+func (s Scope) Allows(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return s == Read || s == Write
+	}
+
+	return s == Write
+}
+
+// TokenPrefix starts every token, so leaked keys are easy to search for.
+const TokenPrefix = "msk_"
+
+// APIKey describes a key. The token itself is shown once, when the key is
+// created; only its hash is stored, and Prefix, its first characters, is
+// what identifies it afterwards.
+type APIKey struct {
+	Id         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scope      Scope      `json:"scope"`
+	CreatedBy  int        `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type apiKeyModel struct {
+	DB     *sql.DB
+	Logger *slog.Logger
+	// Timeout caps each query. Every API call looks its key up first, so
+	// this bounds how long a slow database stalls them. Zero leaves it to
+	// the caller's context.
+	Timeout time.Duration
+}
+
+//go:generate mockgen --source=apikey.go --package=mocks --destination=./mocks/apikey.go  APIKeyModelService
+type APIKeyModelService interface {
+	Create(ctx context.Context, name string, scope Scope, expiresAt *time.Time, createdBy int) (APIKey, string, error)
+	List(ctx context.Context) ([]APIKey, error)
+	Delete(ctx context.Context, id string) error
+	Authenticate(ctx context.Context, token string) (APIKey, error)
+}
+
+func NewAPIKeyModelService(db *sql.DB, logger *slog.Logger) *apiKeyModel {
+	return &apiKeyModel{
+		DB:     db,
+		Logger: logger,
+	}
+}
+
+const columns = "id, name, prefix, scope, COALESCE(created_by, 0), created_at, expires_at, last_used_at"
+
+// Create stores a new key and returns it with its token.
+func (km *apiKeyModel) Create(ctx context.Context, name string, scope Scope, expiresAt *time.Time, createdBy int) (APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if err := validate(name, scope, expiresAt); err != nil {
+		return APIKey{}, "", err
+	}
+
+	token := newToken()
+
+	ctx, cancel := km.withTimeout(ctx)
+	defer cancel()
+
+	row := km.DB.QueryRowContext(ctx, "INSERT INTO api_key(name, prefix, key_hash, scope, created_by, expires_at) VALUES($1, $2, $3, $4, $5, $6) RETURNING "+columns,
+		name, prefix(token), hashToken(token), scope, createdBy, expiresAt)
+	k, err := scan(row)
+	if err != nil {
+		return k, "", km.translate(ctx, "create", err)
+	}
+
+	return k, token, nil
+}
+
+// List returns every key, newest first.
+func (km *apiKeyModel) List(ctx context.Context) ([]APIKey, error) {
+	keys := []APIKey{}
+
+	ctx, cancel := km.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := km.DB.QueryContext(ctx, "SELECT "+columns+" FROM api_key ORDER BY id DESC")
+	if err != nil {
+		return keys, km.translate(ctx, "list", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		k, err := scan(rows)
+		if err != nil {
+			return keys, err
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, km.translate(ctx, "list", rows.Err())
+}
+
+// Delete revokes a key; requests using it fail from then on.
+func (km *apiKeyModel) Delete(ctx context.Context, id string) error {
+	key, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	ctx, cancel := km.withTimeout(ctx)
+	defer cancel()
+
+	res, err := km.DB.ExecContext(ctx, "DELETE FROM api_key WHERE id = $1", key)
+	if err != nil {
+		return km.translate(ctx, "delete", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Authenticate returns the live key behind token and records that it was
+// just used.
+func (km *apiKeyModel) Authenticate(ctx context.Context, token string) (APIKey, error) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return APIKey{}, ErrUnknown
+	}
+
+	ctx, cancel := km.withTimeout(ctx)
+	defer cancel()
+
+	row := km.DB.QueryRowContext(ctx, "UPDATE api_key SET last_used_at = now() WHERE key_hash = $1 AND (expires_at IS NULL OR expires_at > now()) RETURNING "+columns,
+		hashToken(token))
+	k, err := scan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return k, ErrUnknown
+	}
+
+	return k, km.translate(ctx, "authenticate", err)
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scan(row scanner) (APIKey, error) {
+	k := APIKey{}
+	var expiresAt, lastUsedAt sql.NullTime
+
+	err := row.Scan(&k.Id, &k.Name, &k.Prefix, &k.Scope, &k.CreatedBy, &k.CreatedAt, &expiresAt, &lastUsedAt)
+	if expiresAt.Valid {
+		k.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return k, err
+}
+
+// translate also logs the failure under op, as an error unless it is a
+// refused row or a canceled request.
+func (km *apiKeyModel) translate(ctx context.Context, op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	res := translate(ctx, err)
+
+	level := slog.LevelError
+	if errors.Is(res, ErrInvalid) || errors.Is(res, context.Canceled) {
+		level = slog.LevelDebug
+	}
+	km.Logger.Log(ctx, level, logging.APIKeyQueryFailed, "op", op, "error", err)
+
+	return res
+}
+
+func (km *apiKeyModel) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if km.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, km.Timeout)
+}
+
+func validate(name string, scope Scope, expiresAt *time.Time) error {
+	if name == "" || len(name) > 64 {
+		return fmt.Errorf("%w: name must be 1 to 64 characters long", ErrInvalid)
+	}
+	if scope != Read && scope != Write {
+		return fmt.Errorf("%w: scope must be read or write, not %q", ErrInvalid, scope)
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return fmt.Errorf("%w: expiry must be in the future", ErrInvalid)
+	}
+
+	return nil
+}
+
+func newToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+}
+
+// prefix is the part of token shown in lists to tell keys apart.
+func prefix(token string) string {
+	return token[len(TokenPrefix) : len(TokenPrefix)+8]
+}
+
+// hashToken is what a key is stored under. Tokens are random enough that
+// an unsalted hash cannot be brute-forced.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
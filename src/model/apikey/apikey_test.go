@@ -0,0 +1,166 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+var ctx = context.Background()
+
+var rowColumns = []string{"id", "name", "prefix", "scope", "created_by", "created_at", "expires_at", "last_used_at"}
+
+func TestScopeAllows(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, method := range []string{"GET", "HEAD", "OPTIONS"} {
+		assert.True(Read.Allows(method), method)
+		assert.True(Write.Allows(method), method)
+	}
+	for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+		assert.False(Read.Allows(method), method)
+		assert.True(Write.Allows(method), method)
+	}
+	assert.False(Scope("admin").Allows("GET"))
+}
+
+func TestCreate(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	assert.Nil(err)
+	defer db.Close()
+	km := NewAPIKeyModelService(db, logging.Discard())
+	insert := regexp.QuoteMeta("INSERT INTO api_key(name, prefix, key_hash, scope, created_by, expires_at) VALUES($1, $2, $3, $4, $5, $6) RETURNING " + columns)
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("Testing success result", func(t *testing.T) {
+		mock.ExpectQuery(insert).
+			WithArgs("sync", sqlmock.AnyArg(), sqlmock.AnyArg(), Write, 1, nil).
+			WillReturnRows(sqlmock.NewRows(rowColumns).AddRow(4, "sync", "abcdefgh", "write", 1, created, nil, nil))
+
+		k, token, err := km.Create(ctx, " sync ", Write, nil, 1)
+
+		assert.Nil(err)
+		assert.Equal(4, k.Id)
+		assert.Equal(Write, k.Scope)
+		assert.Nil(k.ExpiresAt)
+		assert.True(strings.HasPrefix(token, TokenPrefix))
+		assert.Len(hashToken(token), 64)
+	})
+
+	t.Run("Testing invalid input", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		for _, tc := range []struct {
+			name      string
+			scope     Scope
+			expiresAt *time.Time
+		}{
+			{" ", Read, nil},
+			{strings.Repeat("k", 65), Read, nil},
+			{"sync", "admin", nil},
+			{"sync", Write, &past},
+		} {
+			_, _, err := km.Create(ctx, tc.name, tc.scope, tc.expiresAt, 1)
+			assert.ErrorIs(err, ErrInvalid, tc.name)
+		}
+	})
+
+	t.Run("Testing rejected by the database", func(t *testing.T) {
+		mock.ExpectQuery(insert).WillReturnError(&pq.Error{Code: "23514"})
+
+		_, _, err := km.Create(ctx, "sync", Read, nil, 1)
+
+		assert.ErrorIs(err, ErrInvalid)
+	})
+
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestList(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	assert.Nil(err)
+	defer db.Close()
+	km := NewAPIKeyModelService(db, logging.Discard())
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	expires := created.Add(24 * time.Hour)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + columns + " FROM api_key ORDER BY id DESC")).
+		WillReturnRows(sqlmock.NewRows(rowColumns).
+			AddRow(2, "reports", "ijklmnop", "read", 0, created, expires, created).
+			AddRow(1, "sync", "abcdefgh", "write", 1, created, nil, nil))
+
+	keys, err := km.List(ctx)
+
+	assert.Nil(err)
+	assert.Len(keys, 2)
+	assert.Equal(expires, *keys[0].ExpiresAt)
+	assert.Equal(created, *keys[0].LastUsedAt)
+	assert.Nil(keys[1].LastUsedAt)
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestDelete(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	assert.Nil(err)
+	defer db.Close()
+	km := NewAPIKeyModelService(db, logging.Discard())
+	query := regexp.QuoteMeta("DELETE FROM api_key WHERE id = $1")
+
+	mock.ExpectExec(query).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Nil(km.Delete(ctx, "1"))
+
+	mock.ExpectExec(query).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(km.Delete(ctx, "2"), ErrNotFound)
+
+	assert.ErrorIs(km.Delete(ctx, "two"), ErrInvalid)
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestAuthenticate(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	assert.Nil(err)
+	defer db.Close()
+	km := NewAPIKeyModelService(db, logging.Discard())
+	query := regexp.QuoteMeta("UPDATE api_key SET last_used_at = now() WHERE key_hash = $1 AND (expires_at IS NULL OR expires_at > now()) RETURNING " + columns)
+	token := TokenPrefix + strings.Repeat("a", 43)
+	used := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("Testing success result", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs(hashToken(token)).
+			WillReturnRows(sqlmock.NewRows(rowColumns).AddRow(1, "sync", "aaaaaaaa", "write", 1, used, nil, used))
+
+		k, err := km.Authenticate(ctx, token)
+
+		assert.Nil(err)
+		assert.Equal("aaaaaaaa", k.Prefix)
+		assert.Equal(used, *k.LastUsedAt)
+	})
+
+	t.Run("Testing unknown or expired key", func(t *testing.T) {
+		mock.ExpectQuery(query).WithArgs(hashToken(token)).WillReturnError(sql.ErrNoRows)
+
+		_, err := km.Authenticate(ctx, token)
+
+		assert.ErrorIs(err, ErrUnknown)
+	})
+
+	t.Run("Testing malformed token", func(t *testing.T) {
+		_, err := km.Authenticate(ctx, "session-token")
+
+		assert.ErrorIs(err, ErrUnknown)
+	})
+
+	assert.Nil(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,37 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrNotFound = errors.New("api key not found")
+	ErrInvalid  = errors.New("invalid api key")
+	// ErrUnknown means a token matches no live key: it never existed, was
+	// revoked or has expired.
+	ErrUnknown = errors.New("unknown or expired api key")
+)
+
+// translate reports a key row the schema refuses as ErrInvalid; nothing
+// else a key query does maps to a sentinel. A query cut short by its
+// context reports the context's error.
+func translate(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%w: %v", ctxErr, err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && (pqErr.Code.Class() == "22" || pqErr.Code.Class() == "23") {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	return err
+}
@@ -0,0 +1,120 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryModel is an APIKeyModelService kept in process memory, for the
+// memory product store. It is safe for concurrent use.
+type memoryModel struct {
+	mu     sync.Mutex
+	lastId int
+	keys   map[string]APIKey
+	now    func() time.Time
+}
+
+func NewMemoryAPIKeyModelService() *memoryModel {
+	return &memoryModel{
+		keys: map[string]APIKey{},
+		now:  time.Now,
+	}
+}
+
+func (mem *memoryModel) Create(ctx context.Context, name string, scope Scope, expiresAt *time.Time, createdBy int) (APIKey, string, error) {
+	if err := ctx.Err(); err != nil {
+		return APIKey{}, "", err
+	}
+
+	name = strings.TrimSpace(name)
+	if err := validate(name, scope, expiresAt); err != nil {
+		return APIKey{}, "", err
+	}
+
+	token := newToken()
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	mem.lastId++
+	k := APIKey{
+		Id:        mem.lastId,
+		Name:      name,
+		Prefix:    prefix(token),
+		Scope:     scope,
+		CreatedBy: createdBy,
+		CreatedAt: mem.now(),
+		ExpiresAt: expiresAt,
+	}
+	mem.keys[hashToken(token)] = k
+
+	return k, token, nil
+}
+
+func (mem *memoryModel) List(ctx context.Context) ([]APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	keys := []APIKey{}
+	for _, k := range mem.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Id > keys[j].Id
+	})
+
+	return keys, nil
+}
+
+func (mem *memoryModel) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	key, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	for hash, k := range mem.keys {
+		if k.Id == key {
+			delete(mem.keys, hash)
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (mem *memoryModel) Authenticate(ctx context.Context, token string) (APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return APIKey{}, err
+	}
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	hash := hashToken(token)
+	k, ok := mem.keys[hash]
+	now := mem.now()
+	if !ok || k.ExpiresAt != nil && !k.ExpiresAt.After(now) {
+		return APIKey{}, ErrUnknown
+	}
+
+	k.LastUsedAt = &now
+	mem.keys[hash] = k
+
+	return k, nil
+}
@@ -0,0 +1,57 @@
+package apikey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryAPIKeys(t *testing.T) {
+	assert := assert.New(t)
+	km := NewMemoryAPIKeyModelService()
+	now := time.Now()
+	km.now = func() time.Time { return now }
+
+	expires := now.Add(time.Hour)
+	read, readToken, err := km.Create(ctx, "reports", Read, &expires, 1)
+	assert.Nil(err)
+	assert.Equal(1, read.Id)
+	assert.Equal(readToken[len(TokenPrefix):len(TokenPrefix)+8], read.Prefix)
+
+	_, writeToken, err := km.Create(ctx, "sync", Write, nil, 1)
+	assert.Nil(err)
+
+	_, _, err = km.Create(ctx, "sync", "admin", nil, 1)
+	assert.ErrorIs(err, ErrInvalid)
+
+	keys, err := km.List(ctx)
+	assert.Nil(err)
+	assert.Equal([]string{"sync", "reports"}, []string{keys[0].Name, keys[1].Name})
+	assert.Nil(keys[0].LastUsedAt)
+
+	k, err := km.Authenticate(ctx, writeToken)
+	assert.Nil(err)
+	assert.Equal(now, *k.LastUsedAt)
+
+	_, err = km.Authenticate(ctx, writeToken+"x")
+	assert.ErrorIs(err, ErrUnknown)
+
+	t.Run("Testing expiry", func(t *testing.T) {
+		_, err := km.Authenticate(ctx, readToken)
+		assert.Nil(err)
+
+		now = expires
+		_, err = km.Authenticate(ctx, readToken)
+		assert.ErrorIs(err, ErrUnknown)
+	})
+
+	t.Run("Testing revoke", func(t *testing.T) {
+		assert.Nil(km.Delete(ctx, "2"))
+		assert.ErrorIs(km.Delete(ctx, "2"), ErrNotFound)
+		assert.ErrorIs(km.Delete(ctx, "two"), ErrInvalid)
+
+		_, err := km.Authenticate(ctx, writeToken)
+		assert.ErrorIs(err, ErrUnknown)
+	})
+}
@@ -0,0 +1,108 @@
+package product
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// The changes the audit log records.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// SystemActor is recorded for changes made with a context that names no
+// actor, such as those of a script or a test.
+const SystemActor = "system"
+
+// AuditEntry is one change to a product: who made it, in which request, and
+// the fields it changed as they were before and after. Before is null for a
+// creation and After for a deletion.
+type AuditEntry struct {
+	Id        int             `json:"id"`
+	ProductId int             `json:"product_id"`
+	Action    string          `json:"action"`
+	Actor     string          `json:"actor"`
+	RequestId string          `json:"request_id"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Actor is who the changes made with a context are recorded against, such
+// as "user:ana" or "api_key:1a2b3c4d", and the request they were made in.
+type Actor struct {
+	Name      string
+	RequestId string
+}
+
+type actorKey struct{}
+
+// WithActor stores a in ctx for the audit log.
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+// ActorFrom returns the actor stored by WithActor, or SystemActor.
+func ActorFrom(ctx context.Context) Actor {
+	a, ok := ctx.Value(actorKey{}).(Actor)
+	if !ok || a.Name == "" {
+		a.Name = SystemActor
+	}
+
+	return a
+}
+
+// diff returns the JSON fields of before and after that differ, as two
+// objects. A nil product has no object; an unchanged field is in neither.
+func diff(before, after *Product) (json.RawMessage, json.RawMessage, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if b != nil && a != nil {
+		for k, v := range b {
+			if bytes.Equal(v, a[k]) {
+				delete(b, k)
+				delete(a, k)
+			}
+		}
+	}
+
+	rawBefore, err := object(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	rawAfter, err := object(a)
+	return rawBefore, rawAfter, err
+}
+
+func fields(p *Product) (map[string]json.RawMessage, error) {
+	if p == nil {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[string]json.RawMessage{}
+	return m, json.Unmarshal(raw, &m)
+}
+
+func object(m map[string]json.RawMessage) (json.RawMessage, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(m)
+}
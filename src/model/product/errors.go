@@ -0,0 +1,47 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrNotFound = errors.New("product not found")
+	ErrConflict = errors.New("product conflicts with an existing one")
+	ErrInvalid  = errors.New("invalid product")
+)
+
+// translate says why a product query failed: a value out of range, such as
+// a price too large for its column, is ErrInvalid, a duplicate or dangling
+// reference ErrConflict, and any other row the schema refuses ErrInvalid.
+// A query cut short by its context reports the context's error.
+func translate(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%w: %v", ctxErr, err)
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code.Class() {
+	case "22":
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	case "23":
+		switch pqErr.Code.Name() {
+		case "unique_violation", "foreign_key_violation", "exclusion_violation":
+			return fmt.Errorf("%w: %v", ErrConflict, err)
+		}
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	return err
+}
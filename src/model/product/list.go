@@ -0,0 +1,240 @@
+package product
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/silastgoes/mock-store/src/money"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ListOptions selects one page of products. Pagination is either by Offset
+// or, when After holds a cursor from a previous Page, by keyset; the cursor
+// wins when both are given.
+//
+// Query restricts the listing to products whose name or description match
+// it. Such listings are sorted by relevance unless Sort says otherwise, and
+// relevance-sorted pages are only reachable by Offset.
+//
+// Currency restricts the listing to products priced in it. Prices in
+// different currencies don't compare, so MinValue and MaxValue must be in
+// Currency, which they default it to, and sorting by value groups the
+// products by currency first.
+type ListOptions struct {
+	Limit    int
+	Offset   int
+	After    string
+	Query    string
+	Sort     string
+	Desc     bool
+	Currency string
+	MinValue *money.Money
+	MaxValue *money.Money
+	InStock  bool
+}
+
+// Page is one page of a listing. Total counts every product the listing
+// matches. Offset is where the page starts when it was asked for by offset;
+// a page reached by cursor reports 0, as it does not know its position.
+type Page struct {
+	Products   []Product `json:"products"`
+	Total      int       `json:"total"`
+	Limit      int       `json:"limit"`
+	Offset     int       `json:"offset"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// cursor is the decoded form of Page.NextCursor: the sort key and id of the
+// last product on the page.
+type cursor struct {
+	Sort  string          `json:"s"`
+	Desc  bool            `json:"d,omitempty"`
+	Id    int             `json:"id"`
+	Value json.RawMessage `json:"v,omitempty"`
+}
+
+const sortRelevance = "relevance"
+
+var sortColumns = map[string]bool{
+	sortRelevance: true,
+	"id":          true,
+	"name":        true,
+	"value":       true,
+	"quantity":    true,
+}
+
+// normalize fills in defaults and rejects options no backend can honour.
+func (opts ListOptions) normalize() (ListOptions, *cursor, error) {
+	opts.Query = strings.TrimSpace(opts.Query)
+
+	switch {
+	case opts.Sort == "" && opts.Query != "":
+		opts.Sort = sortRelevance
+	case opts.Sort == "":
+		opts.Sort = "id"
+	case !sortColumns[opts.Sort]:
+		return opts, nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalid, opts.Sort)
+	case opts.Sort == sortRelevance && opts.Query == "":
+		return opts, nil, fmt.Errorf("%w: sorting by relevance needs a search query", ErrInvalid)
+	}
+
+	switch {
+	case opts.Limit < 0 || opts.Offset < 0:
+		return opts, nil, fmt.Errorf("%w: limit and offset must not be negative", ErrInvalid)
+	case opts.Limit == 0:
+		opts.Limit = DefaultPageSize
+	case opts.Limit > MaxPageSize:
+		opts.Limit = MaxPageSize
+	}
+
+	for _, v := range []*money.Money{opts.MinValue, opts.MaxValue} {
+		switch {
+		case v == nil:
+		case opts.Currency == "":
+			opts.Currency = v.Currency
+		case v.Currency != opts.Currency:
+			return opts, nil, fmt.Errorf("%w: price filters must be in %s", ErrInvalid, opts.Currency)
+		}
+	}
+	if opts.Currency != "" && !money.Known(opts.Currency) {
+		return opts, nil, fmt.Errorf("%w: unknown currency %q", ErrInvalid, opts.Currency)
+	}
+
+	if opts.MinValue != nil && opts.MaxValue != nil && opts.MinValue.Cmp(*opts.MaxValue) > 0 {
+		return opts, nil, fmt.Errorf("%w: minimum value is above the maximum", ErrInvalid)
+	}
+
+	if opts.After == "" {
+		return opts, nil, nil
+	}
+
+	c, err := decodeCursor(opts.After)
+	if err != nil || c.Sort != opts.Sort || c.Desc != opts.Desc || c.Sort == sortRelevance {
+		return opts, nil, fmt.Errorf("%w: cursor does not match this listing", ErrInvalid)
+	}
+	opts.Offset = 0
+
+	return opts, c, nil
+}
+
+// sortKey is the value of the sort column for p.
+func sortKey(sort string, p Product) interface{} {
+	switch sort {
+	case "name":
+		return p.Name
+	case "value":
+		return p.Value
+	case "quantity":
+		return p.Quantity
+	}
+
+	return p.Id
+}
+
+// encodeCursor returns the keyset cursor that resumes the listing after
+// last, or "" for relevance listings, which have no stable key.
+func encodeCursor(opts ListOptions, last Product) string {
+	if opts.Sort == sortRelevance {
+		return ""
+	}
+
+	c := cursor{Sort: opts.Sort, Desc: opts.Desc, Id: last.Id}
+	if opts.Sort != "id" {
+		c.Value, _ = json.Marshal(sortKey(opts.Sort, last))
+	}
+
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &cursor{}
+	if err = json.Unmarshal(raw, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// key is the cursor's sort value typed like the column it came from.
+func (c *cursor) key() (interface{}, error) {
+	var err error
+
+	switch c.Sort {
+	case "name":
+		var v string
+		err = json.Unmarshal(c.Value, &v)
+		return v, err
+	case "value":
+		var v money.Money
+		err = json.Unmarshal(c.Value, &v)
+		return v, err
+	case "quantity":
+		var v int
+		err = json.Unmarshal(c.Value, &v)
+		return v, err
+	}
+
+	return c.Id, nil
+}
+
+// compare orders two products the way a listing sorted by sort does, using
+// the id to break ties so the order is total. Values are ordered by
+// currency first.
+func compare(sort string, a, b Product) int {
+	switch sort {
+	case "name":
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+	case "value":
+		if c := strings.Compare(a.Value.Currency, b.Value.Currency); c != 0 {
+			return c
+		}
+		if c := a.Value.Cmp(b.Value); c != 0 {
+			return c
+		}
+	case "quantity":
+		if a.Quantity != b.Quantity {
+			return a.Quantity - b.Quantity
+		}
+	}
+
+	return a.Id - b.Id
+}
+
+// matches is the substring search used where there is no full-text index:
+// a case-insensitive match of every query word against name or description.
+// The score favours name matches so it can stand in for a relevance rank.
+func matches(query string, p Product) (int, bool) {
+	name := strings.ToLower(p.Name)
+	description := strings.ToLower(p.Description)
+	score := 0
+
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		inName := strings.Contains(name, word)
+		inDescription := strings.Contains(description, word)
+
+		switch {
+		case inName:
+			score += 2
+		case inDescription:
+			score++
+		default:
+			return 0, false
+		}
+	}
+
+	return score, true
+}
@@ -0,0 +1,250 @@
+package product
+
+import (
+	"regexp"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func seedMemory(t *testing.T) *memoryModel {
+	t.Helper()
+	ps := NewMemoryProductModelService()
+
+	for _, p := range []Product{
+		{Name: "pen", Value: brl(250), Quantity: 10},
+		{Name: "notebook", Value: brl(1200), Quantity: 0},
+		{Name: "eraser", Value: brl(100), Quantity: 3},
+		{Name: "bag", Value: brl(8000), Quantity: 1},
+		{Name: "ruler", Value: brl(250), Quantity: 7},
+	} {
+		_, err := ps.Create(ctx, p.Name, p.Description, p.Value, p.Quantity)
+		assert.Nil(t, err)
+	}
+
+	return ps
+}
+
+func brl(cents int64) money.Money {
+	return money.New(cents, "BRL")
+}
+
+func names(products []Product) []string {
+	res := []string{}
+	for _, p := range products {
+		res = append(res, p.Name)
+	}
+	return res
+}
+
+func TestListOptionsNormalize(t *testing.T) {
+	assert := assert.New(t)
+
+	opts, after, err := ListOptions{}.normalize()
+	assert.Nil(err)
+	assert.Nil(after)
+	assert.Equal(ListOptions{Limit: DefaultPageSize, Sort: "id"}, opts)
+
+	opts, _, err = ListOptions{Query: " pen "}.normalize()
+	assert.Nil(err)
+	assert.Equal("pen", opts.Query)
+	assert.Equal(sortRelevance, opts.Sort)
+
+	opts, _, err = ListOptions{Limit: 1000}.normalize()
+	assert.Nil(err)
+	assert.Equal(MaxPageSize, opts.Limit)
+
+	low := brl(100)
+	opts, _, err = ListOptions{MinValue: &low}.normalize()
+	assert.Nil(err)
+	assert.Equal("BRL", opts.Currency, "price filters pick the currency")
+
+	min, max := brl(1000), brl(100)
+	for _, invalid := range []ListOptions{
+		{Sort: "description"},
+		{Limit: -1},
+		{Offset: -1},
+		{MinValue: &min, MaxValue: &max},
+		{MinValue: &min, Currency: "USD"},
+		{Currency: "XYZ"},
+		{After: "not a cursor"},
+		{After: encodeCursor(ListOptions{Sort: "name"}, Product{Id: 1}), Sort: "value"},
+		{Sort: sortRelevance},
+	} {
+		_, _, err = invalid.normalize()
+		assert.ErrorIs(err, ErrInvalid)
+	}
+}
+
+func TestMemoryListProducts(t *testing.T) {
+	assert := assert.New(t)
+	ps := seedMemory(t)
+
+	t.Run("Testing default order", func(t *testing.T) {
+		page, err := ps.ListProducts(ctx, ListOptions{})
+
+		assert.Nil(err)
+		assert.Equal(5, page.Total)
+		assert.Equal([]string{"pen", "notebook", "eraser", "bag", "ruler"}, names(page.Products))
+		assert.Empty(page.NextCursor)
+	})
+
+	t.Run("Testing sort and filters", func(t *testing.T) {
+		min, max := brl(200), brl(2000)
+		page, err := ps.ListProducts(ctx, ListOptions{Sort: "value", Desc: true, MinValue: &min, MaxValue: &max, InStock: true})
+
+		assert.Nil(err)
+		assert.Equal(2, page.Total)
+		assert.Equal([]string{"ruler", "pen"}, names(page.Products))
+	})
+
+	t.Run("Testing offset pagination", func(t *testing.T) {
+		page, err := ps.ListProducts(ctx, ListOptions{Sort: "name", Limit: 2, Offset: 2})
+
+		assert.Nil(err)
+		assert.Equal([]string{"notebook", "pen"}, names(page.Products))
+		assert.NotEmpty(page.NextCursor)
+	})
+
+	t.Run("Testing keyset pagination", func(t *testing.T) {
+		opts := ListOptions{Sort: "value", Limit: 2}
+		seen := []string{}
+
+		for {
+			page, err := ps.ListProducts(ctx, opts)
+			assert.Nil(err)
+			seen = append(seen, names(page.Products)...)
+			if page.NextCursor == "" {
+				break
+			}
+			opts.After = page.NextCursor
+		}
+
+		assert.Equal([]string{"eraser", "pen", "ruler", "notebook", "bag"}, seen)
+	})
+
+	t.Run("Testing currencies", func(t *testing.T) {
+		ps.Create(ctx, "stapler", "", money.New(300, "USD"), 2)
+		ps.Create(ctx, "tape", "", money.New(100, "EUR"), 2)
+
+		page, err := ps.ListProducts(ctx, ListOptions{Sort: "value"})
+		assert.Nil(err)
+		assert.Equal([]string{"eraser", "pen", "ruler", "notebook", "bag", "tape", "stapler"}, names(page.Products),
+			"values are grouped by currency")
+
+		min := money.New(200, "USD")
+		page, err = ps.ListProducts(ctx, ListOptions{MinValue: &min})
+		assert.Nil(err)
+		assert.Equal([]string{"stapler"}, names(page.Products), "a price filter only matches its currency")
+
+		page, err = ps.ListProducts(ctx, ListOptions{Currency: "EUR"})
+		assert.Nil(err)
+		assert.Equal([]string{"tape"}, names(page.Products))
+	})
+}
+
+func TestMemorySearch(t *testing.T) {
+	assert := assert.New(t)
+	ps := NewMemoryProductModelService()
+
+	ps.Create(ctx, "Blue pen", "writes in blue", brl(200), 1)
+	ps.Create(ctx, "Notebook", "ruled pages, blue cover", brl(1200), 1)
+	ps.Create(ctx, "Eraser", "removes pencil", brl(100), 1)
+	ps.Create(ctx, "Pen case", "holds a blue pen", brl(2000), 1)
+
+	t.Run("Testing relevance", func(t *testing.T) {
+		page, err := ps.ListProducts(ctx, ListOptions{Query: "BLUE"})
+
+		assert.Nil(err)
+		assert.Equal(3, page.Total)
+		assert.Equal([]string{"Blue pen", "Notebook", "Pen case"}, names(page.Products))
+		assert.Empty(page.NextCursor)
+	})
+
+	t.Run("Testing every word must match", func(t *testing.T) {
+		page, err := ps.ListProducts(ctx, ListOptions{Query: "blue pen", Sort: "value", Desc: true})
+
+		assert.Nil(err)
+		assert.Equal([]string{"Pen case", "Blue pen"}, names(page.Products))
+	})
+
+	t.Run("Testing relevance pages by offset", func(t *testing.T) {
+		page, err := ps.ListProducts(ctx, ListOptions{Query: "pen", Limit: 1, Offset: 1})
+
+		assert.Nil(err)
+		assert.Equal(3, page.Total)
+		assert.Equal([]string{"Pen case"}, names(page.Products))
+		assert.Empty(page.NextCursor)
+	})
+}
+
+func TestListProducts(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	defer db.Close()
+	assert.Nil(err)
+
+	coluns := []string{"id", "name", "description", "currency", "value", "quantity"}
+	ps := NewProductModelService(db, logging.Discard())
+
+	t.Run("Testing filters and offset", func(t *testing.T) {
+		min := brl(150)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM product WHERE currency = $1 AND value >= $2 AND quantity > 0")).
+			WithArgs("BRL", "1.50").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, description, currency, value, quantity FROM product WHERE currency = $1 AND value >= $2 AND quantity > 0 ORDER BY name DESC, id DESC LIMIT $3 OFFSET $4")).
+			WithArgs("BRL", "1.50", 3, 4).
+			WillReturnRows(sqlmock.NewRows(coluns).
+				AddRow(3, "pen", "", "BRL", "2.5", 10).
+				AddRow(2, "eraser", "", "BRL", "1.5", 3).
+				AddRow(1, "bag", "", "BRL", "80.0", 1))
+
+		page, err := ps.ListProducts(ctx, ListOptions{Limit: 2, Offset: 4, Sort: "name", Desc: true, MinValue: &min, InStock: true})
+
+		assert.Nil(err)
+		assert.Equal(3, page.Total)
+		assert.Equal([]string{"pen", "eraser"}, names(page.Products))
+		assert.Equal(encodeCursor(ListOptions{Sort: "name", Desc: true}, page.Products[1]), page.NextCursor)
+		assert.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("Testing keyset", func(t *testing.T) {
+		after := encodeCursor(ListOptions{Sort: "value"}, Product{Id: 2, Value: brl(150)})
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM product")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, description, currency, value, quantity FROM product WHERE (currency, value, id) > ($1, $2, $3) ORDER BY currency ASC, value ASC, id ASC LIMIT $4 OFFSET $5")).
+			WithArgs("BRL", "1.50", 2, DefaultPageSize+1, 0).
+			WillReturnRows(sqlmock.NewRows(coluns).AddRow(3, "pen", "", "BRL", "2.5", 10))
+
+		page, err := ps.ListProducts(ctx, ListOptions{Sort: "value", After: after, Offset: 7})
+
+		assert.Nil(err)
+		assert.Equal([]string{"pen"}, names(page.Products))
+		assert.Empty(page.NextCursor)
+		assert.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("Testing search", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM product WHERE search @@ websearch_to_tsquery('simple', $1)")).
+			WithArgs("blue pen").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, description, currency, value, quantity FROM product WHERE search @@ websearch_to_tsquery('simple', $1) ORDER BY ts_rank(search, websearch_to_tsquery('simple', $2)) DESC, id ASC LIMIT $3 OFFSET $4")).
+			WithArgs("blue pen", "blue pen", DefaultPageSize+1, 0).
+			WillReturnRows(sqlmock.NewRows(coluns).AddRow(3, "Blue pen", "", "BRL", "2.5", 10))
+
+		page, err := ps.ListProducts(ctx, ListOptions{Query: "blue pen"})
+
+		assert.Nil(err)
+		assert.Equal([]string{"Blue pen"}, names(page.Products))
+		assert.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("Testing invalid options", func(t *testing.T) {
+		_, err := ps.ListProducts(ctx, ListOptions{Sort: "description"})
+
+		assert.ErrorIs(err, ErrInvalid)
+	})
+}
@@ -0,0 +1,322 @@
+package product
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/money"
+)
+
+// memoryModel is a ProductModelService kept entirely in process memory. It is
+// safe for concurrent use and hands out copies, so callers can never mutate
+// the stored products.
+type memoryModel struct {
+	mu          sync.RWMutex
+	lastId      int
+	products    map[int]Product
+	lastAuditId int
+	history     map[int][]AuditEntry
+	now         func() time.Time
+}
+
+func NewMemoryProductModelService() *memoryModel {
+	return &memoryModel{
+		products: map[int]Product{},
+		history:  map[int][]AuditEntry{},
+		now:      time.Now,
+	}
+}
+
+func (mem *memoryModel) ListProducts(ctx context.Context, opts ListOptions) (Page, error) {
+	if err := ctx.Err(); err != nil {
+		return Page{}, err
+	}
+
+	opts, after, err := opts.normalize()
+	if err != nil {
+		return Page{}, err
+	}
+
+	var last Product
+	if after != nil {
+		key, err := after.key()
+		if err != nil {
+			return Page{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+
+		last.Id = after.Id
+		switch v := key.(type) {
+		case string:
+			last.Name = v
+		case money.Money:
+			last.Value = v
+		case int:
+			if opts.Sort == "quantity" {
+				last.Quantity = v
+			}
+		}
+	}
+
+	mem.mu.RLock()
+	found := []Product{}
+	scores := map[int]int{}
+	for _, p := range mem.products {
+		if opts.Currency != "" && p.Value.Currency != opts.Currency ||
+			opts.MinValue != nil && p.Value.Cmp(*opts.MinValue) < 0 ||
+			opts.MaxValue != nil && p.Value.Cmp(*opts.MaxValue) > 0 ||
+			opts.InStock && p.Quantity <= 0 {
+			continue
+		}
+		if opts.Query != "" {
+			score, ok := matches(opts.Query, p)
+			if !ok {
+				continue
+			}
+			scores[p.Id] = score
+		}
+		found = append(found, p)
+	}
+	mem.mu.RUnlock()
+
+	order := func(a, b Product) int {
+		if opts.Sort == sortRelevance && scores[a.Id] != scores[b.Id] {
+			return scores[b.Id] - scores[a.Id]
+		}
+		if opts.Desc {
+			return compare(opts.Sort, b, a)
+		}
+		return compare(opts.Sort, a, b)
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return order(found[i], found[j]) < 0
+	})
+
+	page := Page{Total: len(found), Limit: opts.Limit, Offset: opts.Offset}
+
+	start := opts.Offset
+	if after != nil {
+		start = sort.Search(len(found), func(i int) bool {
+			return order(found[i], last) > 0
+		})
+	}
+	if start > len(found) {
+		start = len(found)
+	}
+
+	end := start + opts.Limit
+	if end > len(found) {
+		end = len(found)
+	}
+
+	page.Products = found[start:end]
+	if end < len(found) {
+		page.NextCursor = encodeCursor(opts, page.Products[len(page.Products)-1])
+	}
+
+	return page, nil
+}
+
+func (mem *memoryModel) Update(ctx context.Context, id int, name, description string, value money.Money, quantity int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	p := Product{Id: id, Name: name, Description: description, Value: value, Quantity: quantity}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	before, ok := mem.products[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := mem.record(ctx, ActionUpdate, id, &before, &p); err != nil {
+		return err
+	}
+
+	mem.products[id] = p
+	return nil
+}
+
+func (mem *memoryModel) SetQuantity(ctx context.Context, id int, quantity int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if quantity < 0 {
+		return ValidationErrors{"quantity": "must not be negative"}
+	}
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	before, ok := mem.products[id]
+	if !ok {
+		return ErrNotFound
+	}
+	after := before
+	after.Quantity = quantity
+	if err := mem.record(ctx, ActionUpdate, id, &before, &after); err != nil {
+		return err
+	}
+
+	mem.products[id] = after
+	return nil
+}
+
+func (mem *memoryModel) Create(ctx context.Context, name, description string, value money.Money, quantity int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	p := Product{Name: name, Description: description, Value: value, Quantity: quantity}
+	if err := p.Validate(); err != nil {
+		return 0, err
+	}
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	p.Id = mem.lastId + 1
+	if err := mem.record(ctx, ActionCreate, p.Id, nil, &p); err != nil {
+		return 0, err
+	}
+
+	mem.lastId = p.Id
+	mem.products[p.Id] = p
+	return p.Id, nil
+}
+
+func (mem *memoryModel) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	key, err := parseId(id)
+	if err != nil {
+		return err
+	}
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	before, ok := mem.products[key]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := mem.record(ctx, ActionDelete, key, &before, nil); err != nil {
+		return err
+	}
+
+	delete(mem.products, key)
+	return nil
+}
+
+func (mem *memoryModel) Get(ctx context.Context, param string) (Product, error) {
+	if err := ctx.Err(); err != nil {
+		return Product{}, err
+	}
+
+	id, err := parseId(param)
+	if err != nil {
+		return Product{}, err
+	}
+
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	p, ok := mem.products[id]
+	if !ok {
+		return p, ErrNotFound
+	}
+
+	return p, nil
+}
+
+// History lists the changes recorded for the product with id, oldest
+// first, reporting ErrNotFound when it has neither a history nor a product.
+func (mem *memoryModel) History(ctx context.Context, id string) ([]AuditEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	key, err := parseId(id)
+	if err != nil {
+		return nil, err
+	}
+
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	stored := mem.history[key]
+	if _, ok := mem.products[key]; !ok && len(stored) == 0 {
+		return nil, ErrNotFound
+	}
+
+	entries := make([]AuditEntry, len(stored))
+	for i, e := range stored {
+		e.Before = bytes.Clone(e.Before)
+		e.After = bytes.Clone(e.After)
+		entries[i] = e
+	}
+
+	return entries, nil
+}
+
+// record appends a change to the history of the product with id. The
+// caller holds mu for writing.
+func (mem *memoryModel) record(ctx context.Context, action string, id int, before, after *Product) error {
+	b, a, err := diff(before, after)
+	if err != nil {
+		return err
+	}
+
+	actor := ActorFrom(ctx)
+	mem.lastAuditId++
+	mem.history[id] = append(mem.history[id], AuditEntry{
+		Id:        mem.lastAuditId,
+		ProductId: id,
+		Action:    action,
+		Actor:     actor.Name,
+		RequestId: actor.RequestId,
+		Before:    b,
+		After:     a,
+		CreatedAt: mem.now(),
+	})
+
+	return nil
+}
+
+func parseId(param string) (int, error) {
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		return id, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	return id, nil
+}
+
+func (mem *memoryModel) Inventory(ctx context.Context) (Inventory, error) {
+	if err := ctx.Err(); err != nil {
+		return Inventory{}, err
+	}
+
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	inv := Inventory{Products: len(mem.products)}
+	for _, p := range mem.products {
+		inv.Units += p.Quantity
+	}
+
+	return inv, nil
+}
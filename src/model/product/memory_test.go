@@ -0,0 +1,187 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCreateAndGet(t *testing.T) {
+	assert := assert.New(t)
+	ps := NewMemoryProductModelService()
+	result := RandonProduct()
+
+	first, err := ps.Create(ctx, result.Name, result.Description, result.Value, result.Quantity)
+	assert.Nil(err)
+	second, err := ps.Create(ctx, result.Name, result.Description, result.Value, result.Quantity)
+	assert.Nil(err)
+	assert.Equal(1, first)
+	assert.Equal(2, second)
+
+	res, err := ps.Get(ctx, fmt.Sprint(first))
+	assert.Nil(err)
+	result.Id = first
+	assert.Equal(result, res)
+
+	t.Run("Testing missing product", func(t *testing.T) {
+		_, err := ps.Get(ctx, "999")
+		assert.ErrorIs(err, ErrNotFound)
+	})
+
+	t.Run("Testing invalid id", func(t *testing.T) {
+		_, err := ps.Get(ctx, "abc")
+		assert.ErrorIs(err, ErrInvalid)
+	})
+}
+
+func TestMemoryUpdate(t *testing.T) {
+	assert := assert.New(t)
+	ps := NewMemoryProductModelService()
+	result := RandonProduct()
+
+	id, _ := ps.Create(ctx, result.Name, result.Description, result.Value, result.Quantity)
+	err := ps.Update(ctx, id, "name", "description", brl(150), 3)
+	assert.Nil(err)
+
+	res, _ := ps.Get(ctx, fmt.Sprint(id))
+	assert.Equal(Product{Id: id, Name: "name", Description: "description", Value: brl(150), Quantity: 3}, res)
+
+	t.Run("Testing missing product", func(t *testing.T) {
+		assert.ErrorIs(ps.Update(ctx, 999, "name", "description", brl(150), 3), ErrNotFound)
+	})
+}
+
+func TestMemorySetQuantity(t *testing.T) {
+	assert := assert.New(t)
+	ps := NewMemoryProductModelService()
+	result := RandonProduct()
+
+	id, _ := ps.Create(ctx, result.Name, result.Description, result.Value, result.Quantity)
+	assert.Nil(ps.Update(ctx, id, "renamed", result.Description, brl(999), result.Quantity))
+	assert.Nil(ps.SetQuantity(ctx, id, 4))
+
+	res, _ := ps.Get(ctx, fmt.Sprint(id))
+	assert.Equal(Product{Id: id, Name: "renamed", Description: result.Description, Value: brl(999), Quantity: 4}, res,
+		"a concurrent edit of the details is kept")
+
+	entries, _ := ps.History(ctx, fmt.Sprint(id))
+	assert.Equal(fmt.Sprintf(`{"quantity":%d}`, result.Quantity), string(entries[2].Before))
+	assert.Equal(`{"quantity":4}`, string(entries[2].After))
+
+	assert.ErrorIs(ps.SetQuantity(ctx, id, -1), ErrInvalid)
+	assert.ErrorIs(ps.SetQuantity(ctx, 999, 1), ErrNotFound)
+}
+
+func TestMemoryDelete(t *testing.T) {
+	assert := assert.New(t)
+	ps := NewMemoryProductModelService()
+	result := RandonProduct()
+
+	id, _ := ps.Create(ctx, result.Name, result.Description, result.Value, result.Quantity)
+	assert.Nil(ps.Delete(ctx, fmt.Sprint(id)))
+
+	_, err := ps.Get(ctx, fmt.Sprint(id))
+	assert.ErrorIs(err, ErrNotFound)
+	assert.ErrorIs(ps.Delete(ctx, fmt.Sprint(id)), ErrNotFound)
+	assert.ErrorIs(ps.Delete(ctx, "abc"), ErrInvalid)
+}
+
+func TestMemoryHistory(t *testing.T) {
+	assert := assert.New(t)
+	ps := NewMemoryProductModelService()
+	p := RandonProduct()
+	ana := WithActor(ctx, Actor{Name: "user:ana", RequestId: "req-1"})
+
+	id, _ := ps.Create(ana, p.Name, p.Description, p.Value, p.Quantity)
+	assert.Nil(ps.Update(ctx, id, "renamed", p.Description, p.Value, p.Quantity))
+	assert.Nil(ps.Delete(ana, fmt.Sprint(id)))
+
+	entries, err := ps.History(ctx, fmt.Sprint(id))
+	assert.Nil(err)
+	assert.Len(entries, 3)
+
+	assert.Equal(ActionCreate, entries[0].Action)
+	assert.Equal("user:ana", entries[0].Actor)
+	assert.Equal("req-1", entries[0].RequestId)
+	assert.Nil(entries[0].Before)
+	assert.Contains(string(entries[0].After), fmt.Sprintf(`"name":%q`, p.Name))
+
+	assert.Equal(SystemActor, entries[1].Actor)
+	assert.Equal(fmt.Sprintf(`{"name":%q}`, p.Name), string(entries[1].Before))
+	assert.Equal(`{"name":"renamed"}`, string(entries[1].After))
+
+	assert.Equal(ActionDelete, entries[2].Action)
+	assert.Nil(entries[2].After)
+
+	entries[1].After[2] = 'X'
+	again, _ := ps.History(ctx, fmt.Sprint(id))
+	assert.Equal(`{"name":"renamed"}`, string(again[1].After), "callers get copies of the recorded fields")
+
+	t.Run("Testing unknown product", func(t *testing.T) {
+		_, err := ps.History(ctx, "999")
+		assert.ErrorIs(err, ErrNotFound)
+	})
+
+	t.Run("Testing failed change", func(t *testing.T) {
+		assert.ErrorIs(ps.Update(ctx, 999, p.Name, p.Description, p.Value, p.Quantity), ErrNotFound)
+		_, err := ps.History(ctx, "999")
+		assert.ErrorIs(err, ErrNotFound, "nothing is recorded for a change that did not happen")
+	})
+}
+
+func TestMemoryInventory(t *testing.T) {
+	assert := assert.New(t)
+	ps := NewMemoryProductModelService()
+
+	inv, err := ps.Inventory(ctx)
+	assert.Nil(err)
+	assert.Equal(Inventory{}, inv)
+
+	for _, quantity := range []int{3, 0, 7} {
+		result := RandonProduct()
+		ps.Create(ctx, result.Name, result.Description, result.Value, quantity)
+	}
+
+	inv, err = ps.Inventory(ctx)
+	assert.Nil(err)
+	assert.Equal(Inventory{Products: 3, Units: 10}, inv)
+}
+
+func TestMemoryConcurrentAccess(t *testing.T) {
+	assert := assert.New(t)
+	ps := NewMemoryProductModelService()
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := RandonProduct()
+			id, _ := ps.Create(ctx, p.Name, p.Description, p.Value, p.Quantity)
+			ps.Update(ctx, id, p.Name, p.Description, p.Value, p.Quantity+1)
+			ps.ListProducts(ctx, ListOptions{})
+		}()
+	}
+	wg.Wait()
+
+	page, err := ps.ListProducts(ctx, ListOptions{Limit: 50})
+	assert.Nil(err)
+	assert.Equal(50, page.Total)
+	assert.Len(page.Products, 50)
+}
+
+func TestMemoryCanceledContext(t *testing.T) {
+	assert := assert.New(t)
+	ps := NewMemoryProductModelService()
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err := ps.Create(canceled, "name", "description", brl(100), 1)
+	assert.ErrorIs(err, context.Canceled)
+	_, err = ps.ListProducts(canceled, ListOptions{})
+	assert.ErrorIs(err, context.Canceled)
+}
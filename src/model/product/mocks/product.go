@@ -5,10 +5,12 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
 	product "github.com/silastgoes/mock-store/src/model/product"
+	money "github.com/silastgoes/mock-store/src/money"
 )
 
 // MockProductModelService is a mock of ProductModelService interface.
@@ -35,73 +37,118 @@ func (m *MockProductModelService) EXPECT() *MockProductModelServiceMockRecorder
 }
 
 // Create mocks base method.
-func (m *MockProductModelService) Create(name, description string, value float64, quantity int) error {
+func (m *MockProductModelService) Create(ctx context.Context, name, description string, value money.Money, quantity int) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", name, description, value, quantity)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "Create", ctx, name, description, value, quantity)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockProductModelServiceMockRecorder) Create(name, description, value, quantity interface{}) *gomock.Call {
+func (mr *MockProductModelServiceMockRecorder) Create(ctx, name, description, value, quantity interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockProductModelService)(nil).Create), name, description, value, quantity)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockProductModelService)(nil).Create), ctx, name, description, value, quantity)
 }
 
 // Delete mocks base method.
-func (m *MockProductModelService) Delete(id string) error {
+func (m *MockProductModelService) Delete(ctx context.Context, id string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockProductModelServiceMockRecorder) Delete(id interface{}) *gomock.Call {
+func (mr *MockProductModelServiceMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockProductModelService)(nil).Delete), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockProductModelService)(nil).Delete), ctx, id)
 }
 
 // Get mocks base method.
-func (m *MockProductModelService) Get(param string) (product.Product, error) {
+func (m *MockProductModelService) Get(ctx context.Context, param string) (product.Product, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", param)
+	ret := m.ctrl.Call(m, "Get", ctx, param)
 	ret0, _ := ret[0].(product.Product)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Get indicates an expected call of Get.
-func (mr *MockProductModelServiceMockRecorder) Get(param interface{}) *gomock.Call {
+func (mr *MockProductModelServiceMockRecorder) Get(ctx, param interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockProductModelService)(nil).Get), ctx, param)
+}
+
+// History mocks base method.
+func (m *MockProductModelService) History(ctx context.Context, id string) ([]product.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", ctx, id)
+	ret0, _ := ret[0].([]product.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// History indicates an expected call of History.
+func (mr *MockProductModelServiceMockRecorder) History(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockProductModelService)(nil).History), ctx, id)
+}
+
+// Inventory mocks base method.
+func (m *MockProductModelService) Inventory(ctx context.Context) (product.Inventory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Inventory", ctx)
+	ret0, _ := ret[0].(product.Inventory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Inventory indicates an expected call of Inventory.
+func (mr *MockProductModelServiceMockRecorder) Inventory(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockProductModelService)(nil).Get), param)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Inventory", reflect.TypeOf((*MockProductModelService)(nil).Inventory), ctx)
 }
 
-// GetProducts mocks base method.
-func (m *MockProductModelService) GetProducts() ([]product.Product, error) {
+// ListProducts mocks base method.
+func (m *MockProductModelService) ListProducts(ctx context.Context, opts product.ListOptions) (product.Page, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetProducts")
-	ret0, _ := ret[0].([]product.Product)
+	ret := m.ctrl.Call(m, "ListProducts", ctx, opts)
+	ret0, _ := ret[0].(product.Page)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetProducts indicates an expected call of GetProducts.
-func (mr *MockProductModelServiceMockRecorder) GetProducts() *gomock.Call {
+// ListProducts indicates an expected call of ListProducts.
+func (mr *MockProductModelServiceMockRecorder) ListProducts(ctx, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListProducts", reflect.TypeOf((*MockProductModelService)(nil).ListProducts), ctx, opts)
+}
+
+// SetQuantity mocks base method.
+func (m *MockProductModelService) SetQuantity(ctx context.Context, id, quantity int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetQuantity", ctx, id, quantity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetQuantity indicates an expected call of SetQuantity.
+func (mr *MockProductModelServiceMockRecorder) SetQuantity(ctx, id, quantity interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProducts", reflect.TypeOf((*MockProductModelService)(nil).GetProducts))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetQuantity", reflect.TypeOf((*MockProductModelService)(nil).SetQuantity), ctx, id, quantity)
 }
 
 // Update mocks base method.
-func (m *MockProductModelService) Update(id int, name, description string, value float64, quantity int) error {
+func (m *MockProductModelService) Update(ctx context.Context, id int, name, description string, value money.Money, quantity int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", id, name, description, value, quantity)
+	ret := m.ctrl.Call(m, "Update", ctx, id, name, description, value, quantity)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockProductModelServiceMockRecorder) Update(id, name, description, value, quantity interface{}) *gomock.Call {
+func (mr *MockProductModelServiceMockRecorder) Update(ctx, id, name, description, value, quantity interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockProductModelService)(nil).Update), id, name, description, value, quantity)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockProductModelService)(nil).Update), ctx, id, name, description, value, quantity)
 }
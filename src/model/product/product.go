@@ -1,127 +1,454 @@
 package product
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/money"
 )
 
 type Product struct {
-	Id          int
-	Name        string
-	Description string
-	Value       float64
-	Quantity    int
+	Id          int         `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Value       money.Money `json:"value"`
+	Quantity    int         `json:"quantity"`
+}
+
+// Inventory sums up the whole catalogue.
+type Inventory struct {
+	Products int
+	Units    int
 }
 
 type productModel struct {
-	DB *sql.DB
+	DB     *sql.DB
+	Logger *slog.Logger
+	// Timeout caps each query, so a slow database answers a page with 504
+	// instead of hanging it. Zero leaves it to the caller's context.
+	Timeout time.Duration
 }
 
 //go:generate mockgen --source=product.go --package=mocks --destination=./mocks/product.go  ProductService
 type ProductModelService interface {
-	Create(name, description string, value float64, quantity int) error
-	Get(param string) (Product, error)
-	GetProducts() ([]Product, error)
-	Update(id int, name, description string, value float64, quantity int) error
-	Delete(id string) error
+	Create(ctx context.Context, name, description string, value money.Money, quantity int) (int, error)
+	Get(ctx context.Context, param string) (Product, error)
+	ListProducts(ctx context.Context, opts ListOptions) (Page, error)
+	Update(ctx context.Context, id int, name, description string, value money.Money, quantity int) error
+	SetQuantity(ctx context.Context, id int, quantity int) error
+	Delete(ctx context.Context, id string) error
+	Inventory(ctx context.Context) (Inventory, error)
+	History(ctx context.Context, id string) ([]AuditEntry, error)
 }
 
-func NewProductModelService(db *sql.DB) *productModel {
+func NewProductModelService(db *sql.DB, logger *slog.Logger) *productModel {
 	return &productModel{
-		DB: db,
+		DB:     db,
+		Logger: logger,
 	}
 }
 
-func (prod *productModel) GetProducts() (products []Product, err error) {
-	p := Product{}
+func (prod *productModel) ListProducts(ctx context.Context, opts ListOptions) (Page, error) {
+	opts, after, err := opts.normalize()
+	if err != nil {
+		return Page{}, err
+	}
+
+	page := Page{Limit: opts.Limit, Offset: opts.Offset}
+
+	ctx, cancel := prod.withTimeout(ctx)
+	defer cancel()
+
+	where := &conditions{}
+	if opts.Currency != "" {
+		where.add("currency = %s", opts.Currency)
+	}
+	if opts.MinValue != nil {
+		where.add("value >= %s", *opts.MinValue)
+	}
+	if opts.MaxValue != nil {
+		where.add("value <= %s", *opts.MaxValue)
+	}
+	if opts.InStock {
+		where.add("quantity > 0")
+	}
+	if opts.Query != "" {
+		where.add("search @@ websearch_to_tsquery('simple', %s)", opts.Query)
+	}
+
+	err = prod.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM product"+where.String(), where.args...).Scan(&page.Total)
+	if err != nil {
+		return page, prod.translate(ctx, "list", err)
+	}
 
-	rows, err := prod.DB.Query("SELECT * FROM product ORDER BY id ASC")
+	dir, op := "ASC", ">"
+	if opts.Desc {
+		dir, op = "DESC", "<"
+	}
+
+	order := " ORDER BY " + opts.Sort + " " + dir
+	if opts.Sort == "value" {
+		order = " ORDER BY currency " + dir + ", value " + dir
+	}
+	if opts.Sort != "id" {
+		order += ", id " + dir
+	}
+	if opts.Sort == sortRelevance {
+		order = " ORDER BY ts_rank(search, websearch_to_tsquery('simple', " + where.placeholder(opts.Query) + ")) DESC, id " + dir
+	}
+
+	if after != nil {
+		key, err := after.key()
+		if err != nil {
+			return page, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+
+		switch opts.Sort {
+		case "id":
+			where.add("id "+op+" %s", key)
+		case "value":
+			where.add("(currency, value, id) "+op+" (%s, %s, %s)", key.(money.Money).Currency, key, after.Id)
+		default:
+			where.add("("+opts.Sort+", id) "+op+" (%s, %s)", key, after.Id)
+		}
+	}
+
+	limit := where.placeholder(opts.Limit + 1)
+	offset := where.placeholder(opts.Offset)
+	query := "SELECT id, name, description, currency, value, quantity FROM product" + where.String() + order +
+		" LIMIT " + limit + " OFFSET " + offset
+
+	rows, err := prod.DB.QueryContext(ctx, query, where.args...)
 	if err != nil {
-		return
+		return page, prod.translate(ctx, "list", err)
 	}
+	defer rows.Close()
 
+	page.Products = []Product{}
 	for rows.Next() {
-		var id, quantity int
-		var name, description string
-		var value float64
+		p := Product{}
 
-		err = rows.Scan(&id, &name, &description, &value, &quantity)
+		err = rows.Scan(&p.Id, &p.Name, &p.Description, &p.Value.Currency, &p.Value, &p.Quantity)
 		if err != nil {
-			return
+			return page, err
 		}
 
-		p.Id = id
-		p.Name = name
-		p.Description = description
-		p.Value = value
-		p.Quantity = quantity
+		page.Products = append(page.Products, p)
+	}
+	if err = rows.Err(); err != nil {
+		return page, prod.translate(ctx, "list", err)
+	}
 
-		products = append(products, p)
+	if len(page.Products) > opts.Limit {
+		page.Products = page.Products[:opts.Limit]
+		page.NextCursor = encodeCursor(opts, page.Products[opts.Limit-1])
 	}
 
-	return
+	return page, nil
 }
 
 func (prod *productModel) Update(
+	ctx context.Context,
 	id int,
 	name, description string,
-	value float64,
+	value money.Money,
 	quantity int,
 
 ) error {
-	rows, err := prod.DB.Prepare("UPDATE product SET name=$1 , description=$2, value=$3, quantity=$4 WHERE id=$5")
-	if err != nil {
+	p := Product{Id: id, Name: name, Description: description, Value: value, Quantity: quantity}
+	if err := p.Validate(); err != nil {
 		return err
 	}
 
-	rows.Exec(name, description, value, quantity, id)
-	return nil
+	return prod.inTx(ctx, "update", func(ctx context.Context, tx *sql.Tx) error {
+		before, err := lock(ctx, tx, strconv.Itoa(id))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE product SET name=$1 , description=$2, value=$3, currency=$4, quantity=$5 WHERE id=$6",
+			name, description, value, value.Currency, quantity, id)
+		if err != nil {
+			return err
+		}
+
+		return audit(ctx, tx, ActionUpdate, id, &before, &p)
+	})
+}
+
+// SetQuantity changes only the stock of the product with id. Every other
+// field keeps the value it has when the row is locked, so a concurrent edit
+// of the details is never undone.
+func (prod *productModel) SetQuantity(ctx context.Context, id int, quantity int) error {
+	if quantity < 0 {
+		return ValidationErrors{"quantity": "must not be negative"}
+	}
+
+	return prod.inTx(ctx, "set_quantity", func(ctx context.Context, tx *sql.Tx) error {
+		before, err := lock(ctx, tx, strconv.Itoa(id))
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.ExecContext(ctx, "UPDATE product SET quantity=$1 WHERE id=$2", quantity, id); err != nil {
+			return err
+		}
+
+		after := before
+		after.Quantity = quantity
+		return audit(ctx, tx, ActionUpdate, id, &before, &after)
+	})
 }
 
-func (prod *productModel) Create(name, description string, value float64, quantity int) error {
+func (prod *productModel) Create(ctx context.Context, name, description string, value money.Money, quantity int) (int, error) {
+	p := Product{Name: name, Description: description, Value: value, Quantity: quantity}
+	if err := p.Validate(); err != nil {
+		return 0, err
+	}
+
+	err := prod.inTx(ctx, "create", func(ctx context.Context, tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, "INSERT INTO product(name, description, value, currency, quantity) VALUES($1, $2, $3, $4, $5) RETURNING id",
+			name, description, value, value.Currency, quantity).Scan(&p.Id)
+		if err != nil {
+			return err
+		}
 
-	rows, err := prod.DB.Prepare("INSERT INTO product(name, description, value, quantity) VALUES($1, $2, $3, $4)")
+		return audit(ctx, tx, ActionCreate, p.Id, nil, &p)
+	})
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	rows.Exec(name, description, value, quantity)
-	return nil
+	return p.Id, nil
+}
+
+func (prod *productModel) Delete(ctx context.Context, id string) error {
+	return prod.inTx(ctx, "delete", func(ctx context.Context, tx *sql.Tx) error {
+		before, err := lock(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.ExecContext(ctx, "DELETE FROM product WHERE id=$1", before.Id); err != nil {
+			return err
+		}
+
+		return audit(ctx, tx, ActionDelete, before.Id, &before, nil)
+	})
 }
 
-func (prod *productModel) Delete(id string) error {
-	rows, err := prod.DB.Prepare("DELETE FROM product WHERE id=$1")
+func (prod *productModel) Get(ctx context.Context, param string) (Product, error) {
+	p := Product{}
+
+	ctx, cancel := prod.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := prod.DB.QueryContext(ctx, "SELECT id, name, description, currency, value, quantity FROM product WHERE id = $1", param)
 	if err != nil {
-		return err
+		return p, prod.translate(ctx, "get", err)
 	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return p, prod.translate(ctx, "get", err)
+		}
+		return p, ErrNotFound
+	}
+
+	var id, quantity int
+	var name, description string
+	var value money.Money
 
-	rows.Exec(id)
-	return nil
+	err = rows.Scan(&id, &name, &description, &value.Currency, &value, &quantity)
+	if err != nil {
+		return p, prod.translate(ctx, "get", err)
+	}
+
+	p.Id = id
+	p.Name = name
+	p.Description = description
+	p.Value = value
+	p.Quantity = quantity
+
+	return p, nil
 }
 
-func (prod *productModel) Get(param string) (Product, error) {
-	p := Product{}
+func (prod *productModel) Inventory(ctx context.Context) (Inventory, error) {
+	inv := Inventory{}
+
+	ctx, cancel := prod.withTimeout(ctx)
+	defer cancel()
 
-	rows, err := prod.DB.Query("SELECT * FROM product WHERE id = $1", param)
+	err := prod.DB.QueryRowContext(ctx, "SELECT COUNT(*), COALESCE(SUM(quantity), 0) FROM product").Scan(&inv.Products, &inv.Units)
 	if err != nil {
-		return p, err
+		return inv, prod.translate(ctx, "inventory", err)
 	}
 
+	return inv, nil
+}
+
+// History lists the changes recorded for the product with id, oldest
+// first. It reports ErrNotFound only when the product has neither a history
+// nor a row, so a deleted product keeps its history.
+func (prod *productModel) History(ctx context.Context, id string) ([]AuditEntry, error) {
+	ctx, cancel := prod.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := prod.DB.QueryContext(ctx, "SELECT id, product_id, action, actor, before, after, request_id, created_at FROM product_audit WHERE product_id = $1 ORDER BY id ASC", id)
+	if err != nil {
+		return nil, prod.translate(ctx, "history", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
 	for rows.Next() {
-		var id, quantity int
-		var name, description string
-		var value float64
+		e := AuditEntry{}
+		var before, after []byte
 
-		err = rows.Scan(&id, &name, &description, &value, &quantity)
+		err = rows.Scan(&e.Id, &e.ProductId, &e.Action, &e.Actor, &before, &after, &e.RequestId, &e.CreatedAt)
 		if err != nil {
-			return p, err
+			return nil, err
 		}
+		e.Before, e.After = before, after
 
-		p.Id = id
-		p.Name = name
-		p.Description = description
-		p.Value = value
-		p.Quantity = quantity
+		entries = append(entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, prod.translate(ctx, "history", err)
 	}
 
-	return p, nil
+	if len(entries) == 0 {
+		var exists bool
+		err = prod.DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM product WHERE id = $1)", id).Scan(&exists)
+		if err != nil {
+			return nil, prod.translate(ctx, "history", err)
+		}
+		if !exists {
+			return nil, ErrNotFound
+		}
+	}
+
+	return entries, nil
+}
+
+// inTx runs fn in a transaction, committing it when fn succeeds. The
+// context fn gets is bounded by Timeout.
+func (prod *productModel) inTx(ctx context.Context, op string, fn func(context.Context, *sql.Tx) error) error {
+	ctx, cancel := prod.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := prod.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return prod.translate(ctx, op, err)
+	}
+
+	if err = fn(ctx, tx); err != nil {
+		tx.Rollback()
+		return prod.translate(ctx, op, err)
+	}
+
+	return prod.translate(ctx, op, tx.Commit())
+}
+
+// lock reads the product with id and locks its row until tx ends.
+func lock(ctx context.Context, tx *sql.Tx, id string) (Product, error) {
+	p := Product{}
+
+	err := tx.QueryRowContext(ctx, "SELECT id, name, description, currency, value, quantity FROM product WHERE id = $1 FOR UPDATE", id).
+		Scan(&p.Id, &p.Name, &p.Description, &p.Value.Currency, &p.Value, &p.Quantity)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, ErrNotFound
+	}
+
+	return p, err
+}
+
+// audit records in tx that the actor of ctx took action on the product with
+// id, changing it from before to after.
+func audit(ctx context.Context, tx *sql.Tx, action string, id int, before, after *Product) error {
+	b, a, err := diff(before, after)
+	if err != nil {
+		return err
+	}
+
+	actor := ActorFrom(ctx)
+	_, err = tx.ExecContext(ctx, "INSERT INTO product_audit(product_id, action, actor, before, after, request_id) VALUES($1, $2, $3, $4, $5, $6)",
+		id, action, actor.Name, jsonArg(b), jsonArg(a), actor.RequestId)
+	return err
+}
+
+// jsonArg passes raw to a JSONB column: as text, since the driver would
+// send bytes as bytea, and as NULL when there is none.
+func jsonArg(raw json.RawMessage) interface{} {
+	if raw == nil {
+		return nil
+	}
+
+	return string(raw)
+}
+
+// translate also logs the driver error under op. Failures that map to no
+// sentinel are the unexpected ones, and only they log as errors.
+func (prod *productModel) translate(ctx context.Context, op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	res := translate(ctx, err)
+
+	level := slog.LevelError
+	if errors.Is(res, ErrNotFound) || errors.Is(res, ErrConflict) || errors.Is(res, ErrInvalid) ||
+		errors.Is(res, context.Canceled) {
+		level = slog.LevelDebug
+	}
+	prod.Logger.Log(ctx, level, logging.ProductQueryFailed, "op", op, "error", err)
+
+	return res
+}
+
+func (prod *productModel) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if prod.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, prod.Timeout)
+}
+
+// conditions accumulates a WHERE clause together with its positional
+// arguments.
+type conditions struct {
+	clauses []string
+	args    []interface{}
+}
+
+// add appends a clause whose %s verbs are replaced by placeholders for args.
+func (c *conditions) add(clause string, args ...interface{}) {
+	placeholders := make([]interface{}, len(args))
+	for i, arg := range args {
+		placeholders[i] = c.placeholder(arg)
+	}
+
+	c.clauses = append(c.clauses, fmt.Sprintf(clause, placeholders...))
+}
+
+func (c *conditions) placeholder(arg interface{}) string {
+	c.args = append(c.args, arg)
+	return "$" + strconv.Itoa(len(c.args))
+}
+
+func (c *conditions) String() string {
+	if len(c.clauses) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(c.clauses, " AND ")
 }
@@ -1,15 +1,23 @@
 package product
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"testing"
+	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/silastgoes/mock-store/src/logging"
 	"github.com/silastgoes/mock-store/src/util"
 	"github.com/stretchr/testify/assert"
 )
 
+var ctx = context.Background()
+
 // RandonProduct generate a random product
 func RandonProduct() Product {
 	return Product{
@@ -17,7 +25,7 @@ func RandonProduct() Product {
 		Name:        util.RandomString(6),
 		Description: util.RandomString(20),
 		Quantity:    util.RandomInt(1, 2000),
-		Value:       util.RandomFloat(),
+		Value:       util.RandomPrice(),
 	}
 }
 
@@ -28,9 +36,9 @@ func TestGet(t *testing.T) {
 	assert.Nil(err)
 
 	rows := &sqlmock.Rows{}
-	coluns := []string{"id", "name", "description", "value", "quantity"}
+	coluns := []string{"id", "name", "description", "currency", "value", "quantity"}
 	result := RandonProduct()
-	ps := NewProductModelService(db)
+	ps := NewProductModelService(db, logging.Discard())
 
 	t.Run("Testing success result", func(t *testing.T) {
 
@@ -39,15 +47,16 @@ func TestGet(t *testing.T) {
 				result.Id,
 				result.Name,
 				result.Description,
-				result.Value,
+				result.Value.Currency,
+				result.Value.Decimal(),
 				result.Quantity,
 			)
 
-		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM product WHERE id = $1`)).
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, description, currency, value, quantity FROM product WHERE id = $1`)).
 			WithArgs(fmt.Sprint(result.Id)).
 			WillReturnRows(rows)
 
-		res, err := ps.Get(fmt.Sprint(result.Id))
+		res, err := ps.Get(ctx, fmt.Sprint(result.Id))
 
 		assert.Nil(err)
 		assert.Equal(res.Id, result.Id)
@@ -57,21 +66,42 @@ func TestGet(t *testing.T) {
 		assert.Equal(res.Value, result.Value)
 	})
 
+	t.Run("Testing not found", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, description, currency, value, quantity FROM product WHERE id = $1`)).
+			WithArgs(fmt.Sprint(result.Id)).
+			WillReturnRows(sqlmock.NewRows(coluns))
+
+		_, err := ps.Get(ctx, fmt.Sprint(result.Id))
+
+		assert.ErrorIs(err, ErrNotFound)
+	})
+
+	t.Run("Testing invalid id", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, description, currency, value, quantity FROM product WHERE id = $1`)).
+			WithArgs("abc").
+			WillReturnError(&pq.Error{Code: "22P02"})
+
+		_, err := ps.Get(ctx, "abc")
+
+		assert.ErrorIs(err, ErrInvalid)
+	})
+
 	t.Run("Testing error Query", func(t *testing.T) {
 		rows = sqlmock.NewRows(coluns).
 			AddRow(
 				"1",
 				result.Name,
 				result.Description,
-				result.Value,
+				result.Value.Currency,
+				result.Value.Decimal(),
 				result.Quantity,
 			)
 
-		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM product WHERE id = $1`)).
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, description, currency, value, quantity FROM product WHERE id = $1`)).
 			WithArgs(2).
 			WillReturnRows(rows)
 
-		_, err := ps.Get(fmt.Sprint(result.Id))
+		_, err := ps.Get(ctx, fmt.Sprint(result.Id))
 
 		assert.Error(err)
 	})
@@ -82,177 +112,377 @@ func TestGet(t *testing.T) {
 				1.5,
 				result.Name,
 				result.Description,
-				result.Value,
+				result.Value.Currency,
+				result.Value.Decimal(),
 				result.Quantity,
 			)
 
-		mock.ExpectQuery(`SELECT * FROM product WHERE id = $1`).
+		mock.ExpectQuery(`SELECT id, name, description, currency, value, quantity FROM product WHERE id = $1`).
 			WithArgs(1.5).
 			WillReturnRows(rows)
 
-		_, err := ps.Get(fmt.Sprint(result.Id))
+		_, err := ps.Get(ctx, fmt.Sprint(result.Id))
 
 		assert.Error(err)
 	})
 }
 
-func TestGetProducts(t *testing.T) {
+const (
+	insertProduct = "INSERT INTO product(name, description, value, currency, quantity) VALUES($1, $2, $3, $4, $5) RETURNING id"
+	lockProduct   = "SELECT id, name, description, currency, value, quantity FROM product WHERE id = $1 FOR UPDATE"
+	insertAudit   = "INSERT INTO product_audit(product_id, action, actor, before, after, request_id) VALUES($1, $2, $3, $4, $5, $6)"
+)
+
+var actorCtx = WithActor(ctx, Actor{Name: "user:ana", RequestId: "req-1"})
+
+func productRow(p Product) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "description", "currency", "value", "quantity"}).
+		AddRow(p.Id, p.Name, p.Description, p.Value.Currency, p.Value.Decimal(), p.Quantity)
+}
+
+func TestCreate(t *testing.T) {
 	assert := assert.New(t)
 	db, mock, err := sqlmock.New()
 	defer db.Close()
 	assert.Nil(err)
 
-	rows := &sqlmock.Rows{}
-	coluns := []string{"id", "name", "description", "value", "quantity"}
 	result := RandonProduct()
-	ps := NewProductModelService(db)
+	ps := NewProductModelService(db, logging.Discard())
 
 	t.Run("Testing success result", func(t *testing.T) {
+		created := result
+		_, after, err := diff(nil, &created)
+		assert.Nil(err)
 
-		rows = sqlmock.NewRows(coluns).
-			AddRow(
-				result.Id,
-				result.Name,
-				result.Description,
-				result.Value,
-				result.Quantity,
-			)
-
-		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM product ORDER BY id ASC`)).
-			WithArgs().
-			WillReturnRows(rows)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(insertProduct)).
+			WithArgs(result.Name, result.Description, result.Value, result.Value.Currency, result.Quantity).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(result.Id))
+		mock.ExpectExec(regexp.QuoteMeta(insertAudit)).
+			WithArgs(result.Id, ActionCreate, "user:ana", nil, string(after), "req-1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 
-		res, err := ps.GetProducts()
+		id, err := ps.Create(actorCtx, result.Name, result.Description, result.Value, result.Quantity)
 
 		assert.Nil(err)
-		assert.Equal(res[0].Id, result.Id)
-		assert.Equal(res[0].Name, result.Name)
-		assert.Equal(res[0].Description, result.Description)
-		assert.Equal(res[0].Quantity, result.Quantity)
-		assert.Equal(res[0].Value, result.Value)
+		assert.Equal(result.Id, id)
+		assert.Nil(mock.ExpectationsWereMet())
 	})
 
-	t.Run("Testing error Query", func(t *testing.T) {
-		rows = sqlmock.NewRows(coluns).
-			AddRow(
-				"1",
-				result.Name,
-				result.Description,
-				result.Value,
-				result.Quantity,
-			)
+	t.Run("Testing conflict", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(insertProduct)).
+			WithArgs(result.Name, result.Description, result.Value, result.Value.Currency, result.Quantity).
+			WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
 
-		mock.ExpectQuery(`SELECT * FROM product ORDER BY id ASC`).
-			WithArgs().
-			WillReturnRows(rows)
+		_, err := ps.Create(ctx, result.Name, result.Description, result.Value, result.Quantity)
 
-		_, err := ps.GetProducts()
+		assert.ErrorIs(err, ErrConflict)
+		assert.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("Testing audit failure", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(insertProduct)).
+			WithArgs(result.Name, result.Description, result.Value, result.Value.Currency, result.Quantity).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(result.Id))
+		mock.ExpectExec(regexp.QuoteMeta(insertAudit)).
+			WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		_, err := ps.Create(ctx, result.Name, result.Description, result.Value, result.Quantity)
 
 		assert.Error(err)
+		assert.Nil(mock.ExpectationsWereMet(), "the product is not kept without its audit entry")
 	})
 }
 
-func TestCreate(t *testing.T) {
+func TestUpdate(t *testing.T) {
 	assert := assert.New(t)
 	db, mock, err := sqlmock.New()
 	defer db.Close()
 	assert.Nil(err)
 
 	result := RandonProduct()
-	ps := NewProductModelService(db)
+	ps := NewProductModelService(db, logging.Discard())
+	update := regexp.QuoteMeta("UPDATE product SET name=$1 , description=$2, value=$3, currency=$4, quantity=$5 WHERE id=$6")
 
 	t.Run("Testing success result", func(t *testing.T) {
+		old := result
+		old.Quantity++
 
-		prepare := regexp.QuoteMeta("INSERT INTO product(name, description, value, quantity) VALUES($1, $2, $3, $4)")
-		mock.ExpectPrepare(prepare).
-			ExpectExec().
-			WithArgs(result.Name, result.Description, result.Value, result.Quantity)
-
-		err := ps.Create(result.Name, result.Description, result.Value, result.Quantity)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockProduct)).
+			WithArgs(fmt.Sprint(result.Id)).
+			WillReturnRows(productRow(old))
+		mock.ExpectExec(update).
+			WithArgs(result.Name, result.Description, result.Value, result.Value.Currency, result.Quantity, result.Id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(insertAudit)).
+			WithArgs(result.Id, ActionUpdate, "user:ana",
+				fmt.Sprintf(`{"quantity":%d}`, old.Quantity), fmt.Sprintf(`{"quantity":%d}`, result.Quantity), "req-1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := ps.Update(actorCtx, result.Id, result.Name, result.Description, result.Value, result.Quantity)
 
 		assert.Nil(err)
+		assert.Nil(mock.ExpectationsWereMet())
 	})
 
-	t.Run("Testing Error", func(t *testing.T) {
+	t.Run("Testing not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockProduct)).
+			WithArgs(fmt.Sprint(result.Id)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		err := ps.Update(ctx, result.Id, result.Name, result.Description, result.Value, result.Quantity)
 
-		prepare := "INSERT INTO product(name, description, value, quantity) VALUES($1, $2, $3, $4)"
-		mock.ExpectPrepare(prepare).
-			ExpectExec().
-			WithArgs(result.Name, result.Description, result.Value, result.Quantity)
+		assert.ErrorIs(err, ErrNotFound)
+		assert.Nil(mock.ExpectationsWereMet())
+	})
 
-		err := ps.Create(result.Name, result.Description, result.Value, result.Quantity)
+	t.Run("Testing invalid value", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockProduct)).
+			WithArgs(fmt.Sprint(result.Id)).
+			WillReturnRows(productRow(result))
+		mock.ExpectExec(update).
+			WithArgs(result.Name, result.Description, result.Value, result.Value.Currency, result.Quantity, result.Id).
+			WillReturnError(&pq.Error{Code: "23514"})
+		mock.ExpectRollback()
+
+		err := ps.Update(ctx, result.Id, result.Name, result.Description, result.Value, result.Quantity)
+
+		assert.ErrorIs(err, ErrInvalid)
+		assert.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("Testing Error", func(t *testing.T) {
+		mock.ExpectBegin().WillReturnError(errors.New("connection reset"))
+
+		err := ps.Update(ctx, result.Id, result.Name, result.Description, result.Value, result.Quantity)
 
 		assert.Error(err)
+	})
+}
+
+func TestSetQuantity(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	defer db.Close()
+	assert.Nil(err)
+
+	result := RandonProduct()
+	ps := NewProductModelService(db, logging.Discard())
+	update := regexp.QuoteMeta("UPDATE product SET quantity=$1 WHERE id=$2")
 
+	t.Run("Testing success result", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockProduct)).
+			WithArgs(fmt.Sprint(result.Id)).
+			WillReturnRows(productRow(result))
+		mock.ExpectExec(update).
+			WithArgs(result.Quantity+1, result.Id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(insertAudit)).
+			WithArgs(result.Id, ActionUpdate, "user:ana",
+				fmt.Sprintf(`{"quantity":%d}`, result.Quantity), fmt.Sprintf(`{"quantity":%d}`, result.Quantity+1), "req-1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := ps.SetQuantity(actorCtx, result.Id, result.Quantity+1)
+
+		assert.Nil(err)
+		assert.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("Testing not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockProduct)).
+			WithArgs(fmt.Sprint(result.Id)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		err := ps.SetQuantity(ctx, result.Id, 1)
+
+		assert.ErrorIs(err, ErrNotFound)
+		assert.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("Testing negative quantity", func(t *testing.T) {
+		err := ps.SetQuantity(ctx, result.Id, -1)
+
+		assert.ErrorIs(err, ErrInvalid)
+		assert.Nil(mock.ExpectationsWereMet())
 	})
 }
 
-func TestUpdate(t *testing.T) {
+func TestDelete(t *testing.T) {
 	assert := assert.New(t)
 	db, mock, err := sqlmock.New()
 	defer db.Close()
 	assert.Nil(err)
 
 	result := RandonProduct()
-	ps := NewProductModelService(db)
+	ps := NewProductModelService(db, logging.Discard())
 
 	t.Run("Testing success result", func(t *testing.T) {
+		old := result
+		before, _, err := diff(&old, nil)
+		assert.Nil(err)
 
-		prepare := regexp.QuoteMeta("UPDATE product SET name=$1 , description=$2, value=$3, quantity=$4 WHERE id=$5")
-		mock.ExpectPrepare(prepare).
-			ExpectExec().
-			WithArgs(result.Name, result.Description, result.Value, result.Quantity, result.Id)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockProduct)).
+			WithArgs(fmt.Sprint(result.Id)).
+			WillReturnRows(productRow(result))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM product WHERE id=$1")).
+			WithArgs(result.Id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(insertAudit)).
+			WithArgs(result.Id, ActionDelete, SystemActor, string(before), nil, "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 
-		err := ps.Update(result.Id, result.Name, result.Description, result.Value, result.Quantity)
+		err = ps.Delete(ctx, fmt.Sprint(result.Id))
 
 		assert.Nil(err)
+		assert.Nil(mock.ExpectationsWereMet())
 	})
 
-	t.Run("Testing Error", func(t *testing.T) {
+	t.Run("Testing not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockProduct)).
+			WithArgs(fmt.Sprint(result.Id)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
 
-		prepare := "UPDATE product SET name=$1 , description=$2, value=$3, quantity=$4 WHERE id=$5"
-		mock.ExpectPrepare(prepare).
-			ExpectExec().
-			WithArgs(result.Name, result.Description, result.Value, result.Quantity, result.Id)
+		err := ps.Delete(ctx, fmt.Sprint(result.Id))
 
-		err := ps.Update(result.Id, result.Name, result.Description, result.Value, result.Quantity)
+		assert.ErrorIs(err, ErrNotFound)
+		assert.Nil(mock.ExpectationsWereMet())
+	})
 
-		assert.Error(err)
+	t.Run("Testing commit failure", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockProduct)).
+			WithArgs(fmt.Sprint(result.Id)).
+			WillReturnRows(productRow(result))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM product WHERE id=$1")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(insertAudit)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit().WillReturnError(errors.New("connection reset"))
+
+		err := ps.Delete(ctx, fmt.Sprint(result.Id))
 
+		assert.Error(err)
 	})
 }
 
-func TestDelete(t *testing.T) {
+func TestHistory(t *testing.T) {
 	assert := assert.New(t)
 	db, mock, err := sqlmock.New()
 	defer db.Close()
 	assert.Nil(err)
 
-	result := RandonProduct()
-	ps := NewProductModelService(db)
+	ps := NewProductModelService(db, logging.Discard())
+	query := regexp.QuoteMeta("SELECT id, product_id, action, actor, before, after, request_id, created_at FROM product_audit WHERE product_id = $1 ORDER BY id ASC")
+	exists := regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM product WHERE id = $1)")
+	columns := []string{"id", "product_id", "action", "actor", "before", "after", "request_id", "created_at"}
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
 
 	t.Run("Testing success result", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs("7").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(1, 7, ActionCreate, "user:ana", nil, []byte(`{"id":7,"quantity":1}`), "req-1", at).
+				AddRow(2, 7, ActionUpdate, "api_key:1a2b3c4d", []byte(`{"quantity":1}`), []byte(`{"quantity":2}`), "req-2", at))
+
+		entries, err := ps.History(ctx, "7")
 
-		prepare := regexp.QuoteMeta("DELETE FROM product WHERE id=$1")
-		mock.ExpectPrepare(prepare).
-			ExpectExec().
-			WithArgs(fmt.Sprint(result.Id))
+		assert.Nil(err)
+		assert.Len(entries, 2)
+		assert.Nil(entries[0].Before)
+		assert.Equal(`{"quantity":2}`, string(entries[1].After))
+		assert.Equal("api_key:1a2b3c4d", entries[1].Actor)
+	})
 
-		err := ps.Delete(fmt.Sprint(result.Id))
+	t.Run("Testing product without history", func(t *testing.T) {
+		mock.ExpectQuery(query).WithArgs("8").WillReturnRows(sqlmock.NewRows(columns))
+		mock.ExpectQuery(exists).WithArgs("8").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		entries, err := ps.History(ctx, "8")
 
 		assert.Nil(err)
+		assert.Empty(entries)
 	})
 
-	t.Run("Testing Error", func(t *testing.T) {
+	t.Run("Testing not found", func(t *testing.T) {
+		mock.ExpectQuery(query).WithArgs("9").WillReturnRows(sqlmock.NewRows(columns))
+		mock.ExpectQuery(exists).WithArgs("9").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		_, err := ps.History(ctx, "9")
 
-		prepare := "DELETE FROM product WHERE id=$1"
-		mock.ExpectPrepare(prepare).
-			ExpectExec().
-			WithArgs(fmt.Sprint(result.Id))
+		assert.ErrorIs(err, ErrNotFound)
+	})
+
+	t.Run("Testing Error", func(t *testing.T) {
+		mock.ExpectQuery(query).WithArgs("7").WillReturnError(errors.New("connection reset"))
 
-		err := ps.Delete(fmt.Sprint(result.Id))
+		_, err := ps.History(ctx, "7")
 
 		assert.Error(err)
+	})
+}
+
+func TestInventory(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	defer db.Close()
+	assert.Nil(err)
+
+	ps := NewProductModelService(db, logging.Discard())
+	query := regexp.QuoteMeta("SELECT COUNT(*), COALESCE(SUM(quantity), 0) FROM product")
+
+	t.Run("Testing success result", func(t *testing.T) {
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"count", "sum"}).AddRow(3, 42))
+
+		inv, err := ps.Inventory(ctx)
 
+		assert.Nil(err)
+		assert.Equal(Inventory{Products: 3, Units: 42}, inv)
 	})
+
+	t.Run("Testing Error", func(t *testing.T) {
+		mock.ExpectQuery(query).WillReturnError(errors.New("connection reset"))
+
+		_, err := ps.Inventory(ctx)
+
+		assert.Error(err)
+	})
+}
+
+func TestTimeout(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	defer db.Close()
+	assert.Nil(err)
+
+	logs := &bytes.Buffer{}
+	logger, _ := logging.New(logs, "text", "debug")
+	ps := NewProductModelService(db, logger)
+	ps.Timeout = 10 * time.Millisecond
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, description, currency, value, quantity FROM product WHERE id = $1`)).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = ps.Get(ctx, "1")
+
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Contains(logs.String(), "level=ERROR msg=product.query.failed op=get")
 }
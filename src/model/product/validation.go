@@ -0,0 +1,86 @@
+package product
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/silastgoes/mock-store/src/money"
+)
+
+const (
+	MaxNameLength        = 255
+	MaxDescriptionLength = 1000
+	// MaxValue is the first whole amount too large for the NUMERIC(14, 2)
+	// value column, checked here so both stores refuse it alike.
+	MaxValue = 1_000_000_000_000
+)
+
+// ValidationErrors maps a field name to what is wrong with it. It matches
+// ErrInvalid under errors.Is.
+type ValidationErrors map[string]string
+
+func (v ValidationErrors) Error() string {
+	fields := make([]string, 0, len(v))
+	for field := range v {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	msgs := make([]string, len(fields))
+	for i, field := range fields {
+		msgs[i] = field + ": " + v[field]
+	}
+
+	return ErrInvalid.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (v ValidationErrors) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// Validate reports every rule p breaks, or nil when it can be stored.
+func (p Product) Validate() error {
+	errs := ValidationErrors{}
+
+	name := strings.TrimSpace(p.Name)
+	switch {
+	case name == "":
+		errs["name"] = "is required"
+	case utf8.RuneCountInString(p.Name) > MaxNameLength:
+		errs["name"] = "must be at most 255 characters"
+	}
+
+	if utf8.RuneCountInString(p.Description) > MaxDescriptionLength {
+		errs["description"] = "must be at most 1000 characters"
+	}
+
+	switch {
+	case !money.Known(p.Value.Currency):
+		errs["currency"] = "is not supported"
+	case p.Value.Amount < 0:
+		errs["value"] = "must not be negative"
+	case p.Value.Amount >= maxMinor(p.Value.Currency):
+		errs["value"] = "must be less than 1000000000000"
+	}
+
+	if p.Quantity < 0 {
+		errs["quantity"] = "must not be negative"
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// maxMinor is MaxValue in the minor units of currency.
+func maxMinor(currency string) int64 {
+	limit := int64(MaxValue)
+	for i := 0; i < money.Digits(currency); i++ {
+		limit *= 10
+	}
+
+	return limit
+}
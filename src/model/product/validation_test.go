@@ -0,0 +1,61 @@
+package product
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/silastgoes/mock-store/src/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(Product{Name: "pen", Value: brl(250), Quantity: 0}.Validate())
+	assert.Nil(Product{Name: strings.Repeat("é", MaxNameLength), Value: brl(0)}.Validate())
+	assert.Nil(Product{Name: "pen", Value: brl(MaxValue*100 - 1)}.Validate())
+
+	for _, tc := range []struct {
+		product Product
+		field   string
+		message string
+	}{
+		{Product{Name: " \t", Value: brl(0)}, "name", "is required"},
+		{Product{Name: strings.Repeat("a", MaxNameLength+1), Value: brl(0)}, "name", "must be at most 255 characters"},
+		{Product{Name: "pen", Description: strings.Repeat("a", MaxDescriptionLength+1), Value: brl(0)}, "description", "must be at most 1000 characters"},
+		{Product{Name: "pen", Value: brl(-1)}, "value", "must not be negative"},
+		{Product{Name: "pen", Value: brl(MaxValue * 100)}, "value", "must be less than 1000000000000"},
+		{Product{Name: "pen", Value: money.New(100, "XYZ")}, "currency", "is not supported"},
+		{Product{Name: "pen", Value: money.Money{}}, "currency", "is not supported"},
+		{Product{Name: "pen", Value: brl(0), Quantity: -1}, "quantity", "must not be negative"},
+	} {
+		err := tc.product.Validate()
+
+		var errs ValidationErrors
+		assert.True(errors.As(err, &errs))
+		assert.ErrorIs(err, ErrInvalid)
+		assert.Equal(ValidationErrors{tc.field: tc.message}, errs)
+	}
+}
+
+func TestValidationErrorsMessage(t *testing.T) {
+	err := ValidationErrors{"value": "must not be negative", "name": "is required"}
+
+	assert.Equal(t, "invalid product: name: is required; value: must not be negative", err.Error())
+}
+
+func TestMemoryRejectsInvalidProduct(t *testing.T) {
+	assert := assert.New(t)
+	ps := NewMemoryProductModelService()
+
+	_, err := ps.Create(ctx, "", "", brl(100), 1)
+	assert.ErrorIs(err, ErrInvalid)
+
+	id, _ := ps.Create(ctx, "pen", "", brl(100), 1)
+	err = ps.Update(ctx, id, "pen", "", brl(100), -1)
+	assert.ErrorIs(err, ErrInvalid)
+
+	_, err = ps.Create(ctx, "yacht", "", brl(MaxValue*100), 1)
+	assert.ErrorIs(err, ErrInvalid)
+}
@@ -0,0 +1,47 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrNotFound    = errors.New("user not found")
+	ErrConflict    = errors.New("username is already taken")
+	ErrInvalid     = errors.New("invalid user")
+	ErrCredentials = errors.New("wrong username or password")
+)
+
+// translate says why a user query failed: a username already taken is
+// ErrConflict, a session for a user that no longer exists ErrNotFound, and
+// any other row the schema refuses ErrInvalid. A query cut short by its
+// context reports the context's error.
+func translate(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%w: %v", ctxErr, err)
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code.Name() {
+	case "unique_violation":
+		return fmt.Errorf("%w: %v", ErrConflict, err)
+	case "foreign_key_violation":
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	if pqErr.Code.Class() == "22" || pqErr.Code.Class() == "23" {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	return err
+}
@@ -0,0 +1,177 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryModel is a UserModelService kept in process memory, for the memory
+// product store. It is safe for concurrent use.
+type memoryModel struct {
+	mu       sync.RWMutex
+	lastId   int
+	users    map[string]User
+	hashes   map[int]string
+	subjects map[string]int
+	sessions map[string]memorySession
+	now      func() time.Time
+}
+
+type memorySession struct {
+	userId    int
+	expiresAt time.Time
+}
+
+func NewMemoryUserModelService() *memoryModel {
+	return &memoryModel{
+		users:    map[string]User{},
+		hashes:   map[int]string{},
+		subjects: map[string]int{},
+		sessions: map[string]memorySession{},
+		now:      time.Now,
+	}
+}
+
+func (mem *memoryModel) Create(ctx context.Context, username, password string, role Role) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	username = strings.ToLower(username)
+	if err := validate(username, password, role); err != nil {
+		return 0, err
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return 0, err
+	}
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	if _, ok := mem.users[username]; ok {
+		return 0, ErrConflict
+	}
+
+	mem.lastId++
+	mem.users[username] = User{Id: mem.lastId, Username: username, Role: role, CreatedAt: mem.now()}
+	mem.hashes[mem.lastId] = hash
+
+	return mem.lastId, nil
+}
+
+func (mem *memoryModel) Authenticate(ctx context.Context, username, password string) (User, error) {
+	if err := ctx.Err(); err != nil {
+		return User{}, err
+	}
+
+	mem.mu.RLock()
+	u, ok := mem.users[strings.ToLower(username)]
+	hash := mem.hashes[u.Id]
+	mem.mu.RUnlock()
+
+	if !checkPassword(hash, password) || !ok {
+		return User{}, ErrCredentials
+	}
+
+	return u, nil
+}
+
+func (mem *memoryModel) Federate(ctx context.Context, issuer, subject, username string, role Role) (User, error) {
+	if err := ctx.Err(); err != nil {
+		return User{}, err
+	}
+
+	username = strings.ToLower(username)
+	if err := validateIdentity(username, role); err != nil {
+		return User{}, err
+	}
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	key := issuer + " " + subject
+	if id, ok := mem.subjects[key]; ok {
+		for name, u := range mem.users {
+			if u.Id == id {
+				u.Role = role
+				mem.users[name] = u
+				return u, nil
+			}
+		}
+	}
+
+	if _, ok := mem.users[username]; ok {
+		return User{}, ErrConflict
+	}
+
+	mem.lastId++
+	u := User{Id: mem.lastId, Username: username, Role: role, CreatedAt: mem.now()}
+	mem.users[username] = u
+	mem.hashes[u.Id] = ""
+	mem.subjects[key] = u.Id
+
+	return u, nil
+}
+
+func (mem *memoryModel) CreateSession(ctx context.Context, userId int, ttl time.Duration) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	token := newToken()
+	now := mem.now()
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	if _, ok := mem.hashes[userId]; !ok {
+		return "", ErrNotFound
+	}
+
+	for key, s := range mem.sessions {
+		if !s.expiresAt.After(now) {
+			delete(mem.sessions, key)
+		}
+	}
+	mem.sessions[hashToken(token)] = memorySession{userId: userId, expiresAt: now.Add(ttl)}
+
+	return token, nil
+}
+
+func (mem *memoryModel) Session(ctx context.Context, token string) (Session, error) {
+	if err := ctx.Err(); err != nil {
+		return Session{}, err
+	}
+
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	s, ok := mem.sessions[hashToken(token)]
+	if !ok || !s.expiresAt.After(mem.now()) {
+		return Session{}, ErrNotFound
+	}
+
+	for _, u := range mem.users {
+		if u.Id == s.userId {
+			return Session{User: u, ExpiresAt: s.expiresAt}, nil
+		}
+	}
+
+	return Session{}, ErrNotFound
+}
+
+func (mem *memoryModel) DeleteSession(ctx context.Context, token string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	delete(mem.sessions, hashToken(token))
+	return nil
+}
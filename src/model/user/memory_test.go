@@ -0,0 +1,102 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryUsers(t *testing.T) {
+	assert := assert.New(t)
+	um := NewMemoryUserModelService()
+
+	id, err := um.Create(ctx, "Ana", "correct horse", Admin)
+	assert.Nil(err)
+	assert.Equal(1, id)
+
+	_, err = um.Create(ctx, "ana", "another horse", Admin)
+	assert.ErrorIs(err, ErrConflict)
+	_, err = um.Create(ctx, "bob", "short", Admin)
+	assert.ErrorIs(err, ErrInvalid)
+
+	u, err := um.Authenticate(ctx, "ana", "correct horse")
+	assert.Nil(err)
+	assert.Equal("ana", u.Username)
+
+	_, err = um.Authenticate(ctx, "ana", "wrong horse")
+	assert.ErrorIs(err, ErrCredentials)
+	_, err = um.Authenticate(ctx, "bob", "correct horse")
+	assert.ErrorIs(err, ErrCredentials)
+}
+
+func TestMemorySessions(t *testing.T) {
+	assert := assert.New(t)
+	um := NewMemoryUserModelService()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	um.now = func() time.Time { return now }
+
+	id, _ := um.Create(ctx, "ana", "correct horse", Admin)
+
+	_, err := um.CreateSession(ctx, 99, time.Hour)
+	assert.ErrorIs(err, ErrNotFound)
+
+	token, err := um.CreateSession(ctx, id, time.Hour)
+	assert.Nil(err)
+
+	s, err := um.Session(ctx, token)
+	assert.Nil(err)
+	assert.Equal("ana", s.User.Username)
+	assert.Equal(now.Add(time.Hour), s.ExpiresAt)
+
+	_, err = um.Session(ctx, token+"x")
+	assert.ErrorIs(err, ErrNotFound)
+
+	t.Run("Testing expiry", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		_, err := um.Session(ctx, token)
+		assert.ErrorIs(err, ErrNotFound)
+
+		um.CreateSession(ctx, id, time.Hour)
+		assert.Len(um.sessions, 1, "expired sessions are swept")
+	})
+
+	t.Run("Testing logout", func(t *testing.T) {
+		token, _ := um.CreateSession(ctx, id, time.Hour)
+		assert.Nil(um.DeleteSession(ctx, token))
+		_, err := um.Session(ctx, token)
+		assert.ErrorIs(err, ErrNotFound)
+	})
+}
+
+func TestMemoryFederate(t *testing.T) {
+	assert := assert.New(t)
+	um := NewMemoryUserModelService()
+	issuer := "https://id.example.com"
+
+	um.Create(ctx, "bob", "correct horse", Admin)
+
+	u, err := um.Federate(ctx, issuer, "1", "Ana", Clerk)
+	assert.Nil(err)
+	assert.Equal(2, u.Id)
+	assert.Equal(Clerk, u.Role)
+
+	u, err = um.Federate(ctx, issuer, "1", "renamed", Manager)
+	assert.Nil(err)
+	assert.Equal("ana", u.Username)
+	assert.Equal(Manager, u.Role)
+
+	_, err = um.Federate(ctx, issuer, "2", "bob", Viewer)
+	assert.ErrorIs(err, ErrConflict)
+	_, err = um.Federate(ctx, issuer, "2", "b", Viewer)
+	assert.ErrorIs(err, ErrInvalid)
+
+	_, err = um.Authenticate(ctx, "ana", "")
+	assert.ErrorIs(err, ErrCredentials)
+
+	token, err := um.CreateSession(ctx, u.Id, time.Hour)
+	assert.Nil(err)
+	s, err := um.Session(ctx, token)
+	assert.Nil(err)
+	assert.Equal(Manager, s.User.Role)
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"fmt"
+	"regexp"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the shortest password Create accepts. bcrypt ignores
+// everything past 72 bytes, so longer passwords are refused rather than
+// silently truncated.
+const (
+	MinPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{2,63}$`)
+
+// cost is the bcrypt work factor; tests lower it to keep hashing fast.
+var cost = bcrypt.DefaultCost
+
+// dummyHash is compared against when a username is unknown, so a failed
+// login takes as long whether or not the user exists.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+
+func validate(username, password string, role Role) error {
+	if err := validateIdentity(username, role); err != nil {
+		return err
+	}
+	if len(password) < MinPasswordLength || len(password) > maxPasswordLength {
+		return fmt.Errorf("%w: password must be %d to %d bytes long", ErrInvalid, MinPasswordLength, maxPasswordLength)
+	}
+
+	return nil
+}
+
+// validateIdentity checks what every user needs, with or without a password.
+func validateIdentity(username string, role Role) error {
+	if _, ok := permissions[role]; !ok {
+		return fmt.Errorf("%w: unknown role %q", ErrInvalid, role)
+	}
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("%w: username must be 3 to 64 lowercase letters, digits, dots, dashes or underscores", ErrInvalid)
+	}
+
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	return string(hash), err
+}
+
+// checkPassword reports whether password matches hash; an empty hash stands
+// for an unknown user and never matches.
+func checkPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,61 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Role is what a user may do in the store, from least to most trusted.
+type Role string
+
+const (
+	Viewer  Role = "viewer"
+	Clerk   Role = "clerk"
+	Manager Role = "manager"
+	Admin   Role = "admin"
+)
+
+// Roles lists every role from least to most trusted.
+var Roles = []Role{Viewer, Clerk, Manager, Admin}
+
+// Permission is a single action a role may be allowed.
+type Permission string
+
+const (
+	ViewProducts   Permission = "products:view"
+	CreateProducts Permission = "products:create"
+	// EditQuantity allows changing a product's stock and nothing else.
+	EditQuantity Permission = "products:edit_quantity"
+	// EditProducts allows changing every field of a product.
+	EditProducts   Permission = "products:edit"
+	DeleteProducts Permission = "products:delete"
+	ManageAPIKeys  Permission = "api_keys:manage"
+)
+
+var permissions = map[Role][]Permission{
+	Viewer:  {ViewProducts},
+	Clerk:   {ViewProducts, EditQuantity},
+	Manager: {ViewProducts, CreateProducts, EditQuantity, EditProducts, DeleteProducts},
+	Admin:   {ViewProducts, CreateProducts, EditQuantity, EditProducts, DeleteProducts, ManageAPIKeys},
+}
+
+// Can reports whether r is allowed p. Unknown roles are allowed nothing.
+func (r Role) Can(p Permission) bool {
+	for _, allowed := range permissions[r] {
+		if allowed == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseRole returns the role named s, in any case.
+func ParseRole(s string) (Role, error) {
+	r := Role(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := permissions[r]; !ok {
+		return "", fmt.Errorf("%w: unknown role %q (want viewer, clerk, manager or admin)", ErrInvalid, s)
+	}
+
+	return r, nil
+}
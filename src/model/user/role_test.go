@@ -0,0 +1,47 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleCan(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, tc := range []struct {
+		role    Role
+		allowed []Permission
+	}{
+		{Viewer, []Permission{ViewProducts}},
+		{Clerk, []Permission{ViewProducts, EditQuantity}},
+		{Manager, []Permission{ViewProducts, CreateProducts, EditQuantity, EditProducts, DeleteProducts}},
+		{Admin, []Permission{ViewProducts, CreateProducts, EditQuantity, EditProducts, DeleteProducts, ManageAPIKeys}},
+		{"", nil},
+	} {
+		for _, p := range []Permission{ViewProducts, CreateProducts, EditQuantity, EditProducts, DeleteProducts, ManageAPIKeys} {
+			want := false
+			for _, allowed := range tc.allowed {
+				want = want || allowed == p
+			}
+			assert.Equal(want, tc.role.Can(p), string(tc.role)+" "+string(p))
+		}
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, r := range Roles {
+		parsed, err := ParseRole(" " + string(r) + " ")
+		assert.Nil(err)
+		assert.Equal(r, parsed)
+	}
+
+	r, err := ParseRole("MANAGER")
+	assert.Nil(err)
+	assert.Equal(Manager, r)
+
+	_, err = ParseRole("owner")
+	assert.ErrorIs(err, ErrInvalid)
+}
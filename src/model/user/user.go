@@ -0,0 +1,214 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/logging"
+)
+
+type User struct {
+	Id        int       `json:"id"`
+	Username  string    `json:"username"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Session is a signed-in browser. Its token is only ever handed to the
+// browser; the store keeps a hash of it.
+type Session struct {
+	User      User
+	ExpiresAt time.Time
+}
+
+type userModel struct {
+	DB     *sql.DB
+	Logger *slog.Logger
+	// Timeout caps each query, so a slow database fails a login or a
+	// session lookup instead of holding it open. Zero leaves it to the
+	// caller's context.
+	Timeout time.Duration
+}
+
+//go:generate mockgen --source=user.go --package=mocks --destination=./mocks/user.go  UserModelService
+type UserModelService interface {
+	Create(ctx context.Context, username, password string, role Role) (int, error)
+	Authenticate(ctx context.Context, username, password string) (User, error)
+	CreateSession(ctx context.Context, userId int, ttl time.Duration) (string, error)
+	Session(ctx context.Context, token string) (Session, error)
+	DeleteSession(ctx context.Context, token string) error
+	Federate(ctx context.Context, issuer, subject, username string, role Role) (User, error)
+}
+
+func NewUserModelService(db *sql.DB, logger *slog.Logger) *userModel {
+	return &userModel{
+		DB:     db,
+		Logger: logger,
+	}
+}
+
+// Create stores a new user with a bcrypt hash of password.
+func (um *userModel) Create(ctx context.Context, username, password string, role Role) (int, error) {
+	var id int
+
+	username = strings.ToLower(username)
+	if err := validate(username, password, role); err != nil {
+		return id, err
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return id, err
+	}
+
+	ctx, cancel := um.withTimeout(ctx)
+	defer cancel()
+
+	err = um.DB.QueryRowContext(ctx, "INSERT INTO app_user(username, password_hash, role) VALUES($1, $2, $3) RETURNING id", username, hash, role).Scan(&id)
+	return id, um.translate(ctx, "create", err)
+}
+
+// Authenticate returns the user whose password matches, or ErrCredentials
+// whether the username or the password was wrong.
+func (um *userModel) Authenticate(ctx context.Context, username, password string) (User, error) {
+	u := User{}
+	hash := ""
+
+	ctx, cancel := um.withTimeout(ctx)
+	defer cancel()
+
+	err := um.DB.QueryRowContext(ctx, "SELECT id, username, role, created_at, COALESCE(password_hash, '') FROM app_user WHERE username = $1", strings.ToLower(username)).
+		Scan(&u.Id, &u.Username, &u.Role, &u.CreatedAt, &hash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return User{}, um.translate(ctx, "authenticate", err)
+	}
+
+	if !checkPassword(hash, password) {
+		return User{}, ErrCredentials
+	}
+
+	return u, nil
+}
+
+// Federate returns the user issuer knows as subject, creating it as
+// username on first sign-in. The issuer decides the role, so it is updated
+// on every sign-in. Such users have no password. ErrConflict means username
+// already belongs to someone else.
+func (um *userModel) Federate(ctx context.Context, issuer, subject, username string, role Role) (User, error) {
+	u := User{}
+
+	username = strings.ToLower(username)
+	if err := validateIdentity(username, role); err != nil {
+		return u, err
+	}
+
+	ctx, cancel := um.withTimeout(ctx)
+	defer cancel()
+
+	err := um.DB.QueryRowContext(ctx, `INSERT INTO app_user(username, role, oidc_issuer, oidc_subject) VALUES($1, $2, $3, $4)
+ON CONFLICT (oidc_issuer, oidc_subject) DO UPDATE SET role = EXCLUDED.role
+RETURNING id, username, role, created_at`, username, role, issuer, subject).
+		Scan(&u.Id, &u.Username, &u.Role, &u.CreatedAt)
+
+	return u, um.translate(ctx, "federate", err)
+}
+
+// CreateSession signs userId in for ttl and returns the session's token.
+// Expired sessions are swept on the way.
+func (um *userModel) CreateSession(ctx context.Context, userId int, ttl time.Duration) (string, error) {
+	token := newToken()
+
+	ctx, cancel := um.withTimeout(ctx)
+	defer cancel()
+
+	_, err := um.DB.ExecContext(ctx, "DELETE FROM session WHERE expires_at < now()")
+	if err != nil {
+		return "", um.translate(ctx, "sweep_sessions", err)
+	}
+
+	_, err = um.DB.ExecContext(ctx, "INSERT INTO session(id, user_id, expires_at) VALUES($1, $2, $3)",
+		hashToken(token), userId, time.Now().Add(ttl))
+	if err != nil {
+		return "", um.translate(ctx, "create_session", err)
+	}
+
+	return token, nil
+}
+
+// Session returns the live session behind token, or ErrNotFound once it
+// has expired or been deleted.
+func (um *userModel) Session(ctx context.Context, token string) (Session, error) {
+	s := Session{}
+
+	ctx, cancel := um.withTimeout(ctx)
+	defer cancel()
+
+	err := um.DB.QueryRowContext(ctx, `SELECT u.id, u.username, u.role, u.created_at, s.expires_at
+FROM session s JOIN app_user u ON u.id = s.user_id
+WHERE s.id = $1 AND s.expires_at > now()`, hashToken(token)).
+		Scan(&s.User.Id, &s.User.Username, &s.User.Role, &s.User.CreatedAt, &s.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, ErrNotFound
+	}
+
+	return s, um.translate(ctx, "session", err)
+}
+
+func (um *userModel) DeleteSession(ctx context.Context, token string) error {
+	ctx, cancel := um.withTimeout(ctx)
+	defer cancel()
+
+	_, err := um.DB.ExecContext(ctx, "DELETE FROM session WHERE id = $1", hashToken(token))
+	return um.translate(ctx, "delete_session", err)
+}
+
+// translate also logs the failure under op. The sentinels are answers for
+// the caller, such as a taken username, so only the rest log as errors.
+func (um *userModel) translate(ctx context.Context, op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	res := translate(ctx, err)
+
+	level := slog.LevelError
+	if errors.Is(res, ErrNotFound) || errors.Is(res, ErrConflict) || errors.Is(res, ErrInvalid) ||
+		errors.Is(res, context.Canceled) {
+		level = slog.LevelDebug
+	}
+	um.Logger.Log(ctx, level, logging.UserQueryFailed, "op", op, "error", err)
+
+	return res
+}
+
+func (um *userModel) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if um.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, um.Timeout)
+}
+
+func newToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashToken is the key a session is stored under, so reading the table
+// does not hand out working tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,183 @@
+package user
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ctx = context.Background()
+
+func init() {
+	cost = bcrypt.MinCost
+}
+
+func TestCreate(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	assert.Nil(err)
+	defer db.Close()
+	um := NewUserModelService(db, logging.Discard())
+	insert := regexp.QuoteMeta("INSERT INTO app_user(username, password_hash, role) VALUES($1, $2, $3) RETURNING id")
+
+	t.Run("Testing success result", func(t *testing.T) {
+		mock.ExpectQuery(insert).
+			WithArgs("ana", sqlmock.AnyArg(), Clerk).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+		id, err := um.Create(ctx, "Ana", "correct horse", Clerk)
+
+		assert.Nil(err)
+		assert.Equal(3, id)
+	})
+
+	t.Run("Testing taken username", func(t *testing.T) {
+		mock.ExpectQuery(insert).
+			WithArgs("ana", sqlmock.AnyArg(), Viewer).
+			WillReturnError(&pq.Error{Code: "23505"})
+
+		_, err := um.Create(ctx, "ana", "correct horse", Viewer)
+
+		assert.ErrorIs(err, ErrConflict)
+	})
+
+	t.Run("Testing invalid input", func(t *testing.T) {
+		for _, tc := range []struct {
+			username, password string
+			role               Role
+		}{
+			{"an", "correct horse", Viewer},
+			{"ana smith", "correct horse", Viewer},
+			{"ana", "short", Viewer},
+			{"ana", string(make([]byte, 73)), Viewer},
+			{"ana", "correct horse", "owner"},
+			{"ana", "correct horse", "Admin"},
+		} {
+			_, err := um.Create(ctx, tc.username, tc.password, tc.role)
+			assert.ErrorIs(err, ErrInvalid, tc.username)
+		}
+	})
+
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestAuthenticate(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	assert.Nil(err)
+	defer db.Close()
+	um := NewUserModelService(db, logging.Discard())
+	query := regexp.QuoteMeta("SELECT id, username, role, created_at, COALESCE(password_hash, '') FROM app_user WHERE username = $1")
+	hash, err := hashPassword("correct horse")
+	assert.Nil(err)
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	row := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "username", "role", "created_at", "password_hash"}).AddRow(3, "ana", "clerk", created, hash)
+	}
+
+	mock.ExpectQuery(query).WithArgs("ana").WillReturnRows(row())
+	u, err := um.Authenticate(ctx, "ANA", "correct horse")
+	assert.Nil(err)
+	assert.Equal(User{Id: 3, Username: "ana", Role: Clerk, CreatedAt: created}, u)
+
+	mock.ExpectQuery(query).WithArgs("ana").WillReturnRows(row())
+	_, err = um.Authenticate(ctx, "ana", "wrong horse")
+	assert.ErrorIs(err, ErrCredentials)
+
+	mock.ExpectQuery(query).WithArgs("bob").WillReturnRows(sqlmock.NewRows([]string{"id", "username", "role", "created_at", "password_hash"}))
+	_, err = um.Authenticate(ctx, "bob", "correct horse")
+	assert.ErrorIs(err, ErrCredentials)
+
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestSessions(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	assert.Nil(err)
+	defer db.Close()
+	um := NewUserModelService(db, logging.Discard())
+	query := regexp.QuoteMeta(`SELECT u.id, u.username, u.role, u.created_at, s.expires_at
+FROM session s JOIN app_user u ON u.id = s.user_id
+WHERE s.id = $1 AND s.expires_at > now()`)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM session WHERE expires_at < now()")).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO session(id, user_id, expires_at) VALUES($1, $2, $3)")).
+		WithArgs(sqlmock.AnyArg(), 3, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	token, err := um.CreateSession(ctx, 3, time.Hour)
+	assert.Nil(err)
+	assert.Len(token, 43)
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	expires := created.Add(time.Hour)
+	mock.ExpectQuery(query).
+		WithArgs(hashToken(token)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "role", "created_at", "expires_at"}).AddRow(3, "ana", "admin", created, expires))
+
+	s, err := um.Session(ctx, token)
+	assert.Nil(err)
+	assert.Equal(Session{User: User{Id: 3, Username: "ana", Role: Admin, CreatedAt: created}, ExpiresAt: expires}, s)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM session WHERE id = $1")).
+		WithArgs(hashToken(token)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Nil(um.DeleteSession(ctx, token))
+
+	mock.ExpectQuery(query).
+		WithArgs(hashToken(token)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "role", "created_at", "expires_at"}))
+	_, err = um.Session(ctx, token)
+	assert.ErrorIs(err, ErrNotFound)
+
+	assert.Nil(mock.ExpectationsWereMet())
+}
+
+func TestFederate(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	assert.Nil(err)
+	defer db.Close()
+	um := NewUserModelService(db, logging.Discard())
+	query := regexp.QuoteMeta(`INSERT INTO app_user(username, role, oidc_issuer, oidc_subject) VALUES($1, $2, $3, $4)
+ON CONFLICT (oidc_issuer, oidc_subject) DO UPDATE SET role = EXCLUDED.role
+RETURNING id, username, role, created_at`)
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("Testing success result", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs("ana", Manager, "https://id.example.com", "248289761001").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "role", "created_at"}).AddRow(4, "ana", "manager", created))
+
+		u, err := um.Federate(ctx, "https://id.example.com", "248289761001", "Ana", Manager)
+
+		assert.Nil(err)
+		assert.Equal(User{Id: 4, Username: "ana", Role: Manager, CreatedAt: created}, u)
+	})
+
+	t.Run("Testing username of a local user", func(t *testing.T) {
+		mock.ExpectQuery(query).WillReturnError(&pq.Error{Code: "23505"})
+
+		_, err := um.Federate(ctx, "https://id.example.com", "1", "ana", Viewer)
+
+		assert.ErrorIs(err, ErrConflict)
+	})
+
+	t.Run("Testing invalid input", func(t *testing.T) {
+		_, err := um.Federate(ctx, "https://id.example.com", "1", "ana smith", Viewer)
+		assert.ErrorIs(err, ErrInvalid)
+		_, err = um.Federate(ctx, "https://id.example.com", "1", "ana", "owner")
+		assert.ErrorIs(err, ErrInvalid)
+	})
+
+	assert.Nil(mock.ExpectationsWereMet())
+}
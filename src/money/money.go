@@ -0,0 +1,272 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used wherever an amount arrives without a currency.
+const DefaultCurrency = "BRL"
+
+var (
+	ErrSyntax    = errors.New("not a decimal amount")
+	ErrPrecision = errors.New("too many decimal places")
+	ErrCurrency  = errors.New("unknown currency")
+)
+
+// Money is an exact amount counted in the minor unit of its currency, so
+// R$ 12,50 is Money{Amount: 1250, Currency: "BRL"}.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// currency describes how amounts in a currency are written.
+type currency struct {
+	Digits   int
+	Symbol   string
+	Decimal  string
+	Grouping string
+}
+
+var currencies = map[string]currency{
+	"BRL": {Digits: 2, Symbol: "R$ ", Decimal: ",", Grouping: "."},
+	"EUR": {Digits: 2, Symbol: "€ ", Decimal: ",", Grouping: "."},
+	"USD": {Digits: 2, Symbol: "$", Decimal: ".", Grouping: ","},
+}
+
+// Currencies lists the supported currency codes in alphabetical order.
+func Currencies() []string {
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	return codes
+}
+
+// Known reports whether code is a supported currency.
+func Known(code string) bool {
+	_, ok := currencies[code]
+	return ok
+}
+
+// Digits is how many decimal places amounts in code carry.
+func Digits(code string) int {
+	if c, ok := currencies[code]; ok {
+		return c.Digits
+	}
+
+	return currencies[DefaultCurrency].Digits
+}
+
+func New(amount int64, code string) Money {
+	return Money{Amount: amount, Currency: code}
+}
+
+// Parse reads a plain decimal such as "1234.56" or "1234,56" in the given
+// currency, or DefaultCurrency when code is empty.
+func Parse(s, code string) (Money, error) {
+	if code == "" {
+		code = DefaultCurrency
+	}
+	if !Known(code) {
+		return Money{}, fmt.Errorf("%w: %q", ErrCurrency, code)
+	}
+
+	amount, err := parseMinor(strings.TrimSpace(s), Digits(code))
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{Amount: amount, Currency: code}, nil
+}
+
+func parseMinor(s string, digits int) (int64, error) {
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, frac := s, ""
+	if i := strings.IndexAny(s, ".,"); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" && frac == "" || !digitsOnly(whole) || !digitsOnly(frac) {
+		return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > digits {
+		return 0, fmt.Errorf("%w: %q", ErrPrecision, s)
+	}
+	frac += strings.Repeat("0", digits-len(frac))
+
+	amount, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+
+	if negative {
+		amount = -amount
+	}
+
+	return amount, nil
+}
+
+func digitsOnly(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Decimal writes m as a plain decimal with a dot, e.g. "1234.56", the form
+// used by HTML number inputs, JSON and SQL.
+func (m Money) Decimal() string {
+	return m.format(".", "")
+}
+
+// String writes m the way its currency is usually shown, e.g. "R$ 1.234,56".
+func (m Money) String() string {
+	c, ok := currencies[m.Currency]
+	if !ok {
+		return strings.TrimSpace(m.Decimal() + " " + m.Currency)
+	}
+
+	s := m.format(c.Decimal, c.Grouping)
+	if strings.HasPrefix(s, "-") {
+		return "-" + c.Symbol + s[1:]
+	}
+
+	return c.Symbol + s
+}
+
+func (m Money) format(decimal, grouping string) string {
+	digits := Digits(m.Currency)
+
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	s := strconv.FormatInt(amount, 10)
+	if len(s) <= digits {
+		s = strings.Repeat("0", digits-len(s)+1) + s
+	}
+	whole, frac := s[:len(s)-digits], s[len(s)-digits:]
+
+	if grouping != "" {
+		for i := len(whole) - 3; i > 0; i -= 3 {
+			whole = whole[:i] + grouping + whole[i:]
+		}
+	}
+
+	if digits == 0 {
+		return sign + whole
+	}
+
+	return sign + whole + decimal + frac
+}
+
+// Cmp compares the amounts of m and o. Amounts in different currencies
+// have no order, so Cmp panics when m and o don't share a currency.
+func (m Money) Cmp(o Money) int {
+	if m.Currency != o.Currency {
+		panic(fmt.Sprintf("money: cannot compare %s with %s", m.Currency, o.Currency))
+	}
+
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+
+	return 0
+}
+
+// Value stores the amount as a decimal for a NUMERIC column. The currency
+// has a column of its own.
+func (m Money) Value() (driver.Value, error) {
+	return m.Decimal(), nil
+}
+
+// Scan reads the amount from a NUMERIC column, keeping the currency m
+// already has.
+func (m *Money) Scan(src interface{}) error {
+	var s string
+
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+
+	amount, err := parseMinor(s, Digits(m.Currency))
+	if err != nil {
+		return err
+	}
+	m.Amount = amount
+
+	return nil
+}
+
+type jsonMoney struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON writes {"amount": "12.50", "currency": "BRL"}; the amount is a
+// string so no client reads it back as a float.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+// UnmarshalJSON accepts the object MarshalJSON writes as well as a bare
+// number or string, which is taken to be in DefaultCurrency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var amount, code string
+
+	switch {
+	case len(data) > 0 && data[0] == '{':
+		v := jsonMoney{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		amount, code = v.Amount, v.Currency
+	case len(data) > 0 && data[0] == '"':
+		if err := json.Unmarshal(data, &amount); err != nil {
+			return err
+		}
+	default:
+		n := json.Number("")
+		if err := json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		amount = n.String()
+	}
+
+	parsed, err := Parse(amount, code)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	for in, amount := range map[string]int64{
+		"0":        0,
+		"12":       1200,
+		"12.5":     1250,
+		"12,50":    1250,
+		".3":       30,
+		"1234.560": 123456,
+		"-0.01":    -1,
+		" 7.00 ":   700,
+	} {
+		m, err := Parse(in, "")
+		assert.Nil(err, in)
+		assert.Equal(New(amount, DefaultCurrency), m, in)
+	}
+
+	_, err := Parse("1.005", "BRL")
+	assert.ErrorIs(err, ErrPrecision)
+
+	for _, in := range []string{"", "-", ".", "abc", "1.2.3", "1e3", "1 000"} {
+		_, err = Parse(in, "BRL")
+		assert.ErrorIs(err, ErrSyntax, in)
+	}
+
+	_, err = Parse("1", "XYZ")
+	assert.ErrorIs(err, ErrCurrency)
+}
+
+func TestSumIsExact(t *testing.T) {
+	a, _ := Parse("0.1", "USD")
+	b, _ := Parse("0.2", "USD")
+
+	assert.Equal(t, "0.30", New(a.Amount+b.Amount, "USD").Decimal())
+}
+
+func TestFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("R$ 1.234,56", New(123456, "BRL").String())
+	assert.Equal("R$ 0,05", New(5, "BRL").String())
+	assert.Equal("-R$ 1.000.000,00", New(-100000000, "BRL").String())
+	assert.Equal("$1,234.56", New(123456, "USD").String())
+	assert.Equal("€ 12,00", New(1200, "EUR").String())
+	assert.Equal("1234.56", New(123456, "BRL").Decimal())
+	assert.Equal("-0.50", New(-50, "BRL").Decimal())
+}
+
+func TestCmp(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(-1, New(100, "BRL").Cmp(New(250, "BRL")))
+	assert.Equal(0, New(100, "BRL").Cmp(New(100, "BRL")))
+	assert.Equal(1, New(250, "BRL").Cmp(New(100, "BRL")))
+	assert.Panics(func() { New(100, "BRL").Cmp(New(100, "USD")) })
+}
+
+func TestSQL(t *testing.T) {
+	assert := assert.New(t)
+
+	v, err := New(1250, "BRL").Value()
+	assert.Nil(err)
+	assert.Equal("12.50", v)
+
+	for _, src := range []interface{}{[]byte("12.50"), "12.5", 12.5} {
+		m := Money{Currency: "BRL"}
+		assert.Nil(m.Scan(src))
+		assert.Equal(New(1250, "BRL"), m)
+	}
+
+	m := Money{Currency: "BRL"}
+	assert.Nil(m.Scan(int64(3)))
+	assert.Equal(int64(300), m.Amount)
+	assert.Error(m.Scan(nil))
+}
+
+func TestJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	raw, err := json.Marshal(New(1250, "USD"))
+	assert.Nil(err)
+	assert.JSONEq(`{"amount":"12.50","currency":"USD"}`, string(raw))
+
+	for in, expected := range map[string]Money{
+		`{"amount":"12.50","currency":"USD"}`: New(1250, "USD"),
+		`12.5`:                                New(1250, DefaultCurrency),
+		`"12.50"`:                             New(1250, DefaultCurrency),
+	} {
+		m := Money{}
+		assert.Nil(json.Unmarshal([]byte(in), &m), in)
+		assert.Equal(expected, m, in)
+	}
+
+	m := Money{}
+	assert.ErrorIs(json.Unmarshal([]byte(`0.305`), &m), ErrPrecision)
+	assert.ErrorIs(json.Unmarshal([]byte(`{"amount":"1","currency":"XYZ"}`), &m), ErrCurrency)
+}
@@ -0,0 +1,276 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/config"
+	"github.com/silastgoes/mock-store/src/model/user"
+)
+
+// ErrProvider means the provider answered with something other than what
+// the protocol promises, or refused the request.
+var ErrProvider = errors.New("oidc provider error")
+
+// Scopes are requested on every login: the ID token needs openid, and
+// profile and email carry the claims a username is made from.
+const Scopes = "openid profile email"
+
+// Provider signs users in through an OpenID Connect provider with the
+// authorization code flow and PKCE. It is safe for concurrent use.
+type Provider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	roleClaim    string
+	roles        map[string]user.Role
+	defaultRole  user.Role
+
+	authURL  string
+	tokenURL string
+	jwksURL  string
+
+	client *http.Client
+	now    func() time.Time
+
+	mu   sync.RWMutex
+	keys map[string]*rsa.PublicKey
+	// fetched is when the keys were last asked for, to bound how often an
+	// unknown key id can make us ask again.
+	fetched time.Time
+}
+
+// discovery is the part of the provider's metadata the flow needs.
+type discovery struct {
+	Issuer   string `json:"issuer"`
+	AuthURL  string `json:"authorization_endpoint"`
+	TokenURL string `json:"token_endpoint"`
+	JWKSURL  string `json:"jwks_uri"`
+}
+
+// Discover reads the provider's metadata from the well-known URL under
+// cfg.Issuer, and its signing keys, with client.
+func Discover(ctx context.Context, cfg config.OIDC, client *http.Client) (*Provider, error) {
+	roles := map[string]user.Role{}
+	for group, name := range cfg.Roles {
+		role, err := user.ParseRole(name)
+		if err != nil {
+			return nil, err
+		}
+		roles[group] = role
+	}
+
+	p := &Provider{
+		issuer:       cfg.Issuer,
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		redirectURL:  cfg.RedirectURL,
+		roleClaim:    cfg.RoleClaim,
+		roles:        roles,
+		client:       client,
+		now:          time.Now,
+	}
+	if cfg.DefaultRole != "" {
+		role, err := user.ParseRole(cfg.DefaultRole)
+		if err != nil {
+			return nil, err
+		}
+		p.defaultRole = role
+	}
+
+	d := discovery{}
+	if err := p.getJSON(ctx, strings.TrimSuffix(cfg.Issuer, "/")+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, err
+	}
+	if d.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("%w: discovery names issuer %q, not %q", ErrProvider, d.Issuer, cfg.Issuer)
+	}
+	if d.AuthURL == "" || d.TokenURL == "" || d.JWKSURL == "" {
+		return nil, fmt.Errorf("%w: discovery lacks an authorization, token or jwks endpoint", ErrProvider)
+	}
+	p.authURL, p.tokenURL, p.jwksURL = d.AuthURL, d.TokenURL, d.JWKSURL
+
+	if err := p.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Issuer is the provider's URL as its ID tokens name it.
+func (p *Provider) Issuer() string {
+	return p.issuer
+}
+
+// Flow is what one login must remember between sending the browser to the
+// provider and its return: State ties the callback to this browser, Nonce
+// ties the ID token to this login and Verifier proves to the token endpoint
+// that the code is being redeemed by whoever asked for it. Next is where the
+// user goes once signed in.
+type Flow struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Next     string `json:"next"`
+}
+
+func NewFlow(next string) Flow {
+	return Flow{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: randomString(),
+		Next:     next,
+	}
+}
+
+// AuthCodeURL is where the browser goes to sign in for f.
+func (p *Provider) AuthCodeURL(f Flow) string {
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.clientID},
+		"redirect_uri":          {p.redirectURL},
+		"scope":                 {Scopes},
+		"state":                 {f.State},
+		"nonce":                 {f.Nonce},
+		"code_challenge":        {Challenge(f.Verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(p.authURL, "?") {
+		sep = "&"
+	}
+
+	return p.authURL + sep + q.Encode()
+}
+
+// Challenge is the S256 PKCE challenge of verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Exchange redeems the code the provider sent back for f and returns the
+// claims of the verified ID token.
+func (p *Provider) Exchange(ctx context.Context, f Flow, code string) (Claims, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"client_id":     {p.clientID},
+		"code_verifier": {f.Verifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Claims{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return Claims{}, err
+	}
+	defer res.Body.Close()
+
+	body := struct {
+		IDToken     string `json:"id_token"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}{}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&body); err != nil {
+		return Claims{}, fmt.Errorf("%w: token response: %v", ErrProvider, err)
+	}
+	if res.StatusCode != http.StatusOK || body.Error != "" {
+		return Claims{}, fmt.Errorf("%w: token endpoint answered %d %s: %s", ErrProvider, res.StatusCode, body.Error, body.Description)
+	}
+	if body.IDToken == "" {
+		return Claims{}, fmt.Errorf("%w: token response has no id_token", ErrProvider)
+	}
+
+	return p.Verify(ctx, body.IDToken, f.Nonce)
+}
+
+// Role is the store role claims earn: the most trusted one any of the
+// values of the role claim maps to, or the default role. It reports false
+// when there is neither.
+func (p *Provider) Role(c Claims) (user.Role, bool) {
+	best := -1
+	for _, group := range c.Strings(p.roleClaim) {
+		role, ok := p.roles[group]
+		if !ok {
+			continue
+		}
+		for i, r := range user.Roles {
+			if r == role && i > best {
+				best = i
+			}
+		}
+	}
+
+	if best >= 0 {
+		return user.Roles[best], true
+	}
+
+	return p.defaultRole, p.defaultRole != ""
+}
+
+// Username is the store username claims suggest: preferred_username, or
+// the part of email before the @.
+func Username(c Claims) string {
+	name := c.PreferredUsername
+	if name == "" {
+		name, _, _ = strings.Cut(c.Email, "@")
+	}
+
+	return strings.ToLower(name)
+}
+
+func (p *Provider) getJSON(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: GET %s answered %d", ErrProvider, target, res.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("%w: GET %s: %v", ErrProvider, target, err)
+	}
+
+	return nil
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,229 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/config"
+	"github.com/silastgoes/mock-store/src/model/user"
+	"github.com/silastgoes/mock-store/src/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+)
+
+var ctx = context.Background()
+
+const redirectURL = "https://store.example.com/login/oidc/callback"
+
+func discover(t *testing.T, fake *oidctest.Provider) *Provider {
+	t.Helper()
+
+	p, err := Discover(ctx, config.OIDC{
+		Issuer:       fake.URL,
+		ClientID:     fake.ClientID,
+		ClientSecret: fake.ClientSecret,
+		RedirectURL:  redirectURL,
+		RoleClaim:    "groups",
+		Roles:        map[string]string{"store-clerks": "clerk", "store-admins": "Admin"},
+		DefaultRole:  "viewer",
+	}, fake.Client())
+	assert.Nil(t, err)
+
+	return p
+}
+
+// authorize follows the browser to the provider and returns the query the
+// provider sends it back with.
+func authorize(t *testing.T, fake *oidctest.Provider, p *Provider, f Flow) url.Values {
+	t.Helper()
+
+	client := fake.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	res, err := client.Get(p.AuthCodeURL(f))
+	assert.Nil(t, err)
+	res.Body.Close()
+
+	back, err := url.Parse(res.Header.Get("Location"))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(back.String(), redirectURL+"?"))
+
+	return back.Query()
+}
+
+func TestDiscover(t *testing.T) {
+	assert := assert.New(t)
+	fake := oidctest.NewProvider("mock-store", "secret")
+	defer fake.Close()
+
+	p := discover(t, fake)
+	assert.Equal(fake.URL, p.Issuer())
+	assert.Equal(fake.URL+"/token", p.tokenURL)
+	assert.Len(p.keys, 1)
+
+	_, err := Discover(ctx, config.OIDC{Issuer: fake.URL + "/other"}, fake.Client())
+	assert.ErrorIs(err, ErrProvider)
+
+	_, err = Discover(ctx, config.OIDC{Issuer: fake.URL, Roles: map[string]string{"x": "owner"}}, fake.Client())
+	assert.ErrorIs(err, user.ErrInvalid)
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	assert := assert.New(t)
+	fake := oidctest.NewProvider("mock-store", "secret")
+	defer fake.Close()
+	p := discover(t, fake)
+
+	fake.SignIn(map[string]interface{}{
+		"sub":                "248289761001",
+		"preferred_username": "Ana",
+		"groups":             []string{"staff", "store-clerks"},
+	})
+
+	f := NewFlow("/products/new")
+	authURL, err := url.Parse(p.AuthCodeURL(f))
+	assert.Nil(err)
+	q := authURL.Query()
+	assert.Equal("code", q.Get("response_type"))
+	assert.Equal(redirectURL, q.Get("redirect_uri"))
+	assert.Equal(Challenge(f.Verifier), q.Get("code_challenge"))
+	assert.NotEqual(f.Verifier, q.Get("code_challenge"))
+
+	t.Run("Testing success", func(t *testing.T) {
+		back := authorize(t, fake, p, f)
+		assert.Equal(f.State, back.Get("state"))
+
+		c, err := p.Exchange(ctx, f, back.Get("code"))
+		assert.Nil(err)
+		assert.Equal("248289761001", c.Subject)
+		assert.Equal(fake.URL, c.Issuer)
+		assert.Equal("ana", Username(c))
+
+		role, ok := p.Role(c)
+		assert.True(ok)
+		assert.Equal(user.Clerk, role)
+
+		_, err = p.Exchange(ctx, f, back.Get("code"))
+		assert.ErrorIs(err, ErrProvider, "codes are redeemed once")
+	})
+
+	t.Run("Testing wrong verifier", func(t *testing.T) {
+		back := authorize(t, fake, p, f)
+
+		other := f
+		other.Verifier = NewFlow("").Verifier
+		_, err := p.Exchange(ctx, other, back.Get("code"))
+		assert.ErrorIs(err, ErrProvider)
+	})
+
+	t.Run("Testing refused login", func(t *testing.T) {
+		fake.SignIn(nil)
+
+		back := authorize(t, fake, p, f)
+		assert.Equal("access_denied", back.Get("error"))
+	})
+}
+
+func TestVerify(t *testing.T) {
+	assert := assert.New(t)
+	fake := oidctest.NewProvider("mock-store", "secret")
+	defer fake.Close()
+	p := discover(t, fake)
+	stranger := oidctest.NewProvider("mock-store", "secret")
+	defer stranger.Close()
+
+	valid := fake.Claims("1", "n-0")
+	c, err := p.Verify(ctx, fake.Sign(valid), "n-0")
+	assert.Nil(err)
+	assert.Equal("1", c.Subject)
+
+	for name, tc := range map[string]struct {
+		change func(map[string]interface{})
+		nonce  string
+	}{
+		"issuer":     {func(c map[string]interface{}) { c["iss"] = "https://evil.example" }, "n-0"},
+		"audience":   {func(c map[string]interface{}) { c["aud"] = "other" }, "n-0"},
+		"azp":        {func(c map[string]interface{}) { c["aud"] = []string{"mock-store", "other"} }, "n-0"},
+		"subject":    {func(c map[string]interface{}) { delete(c, "sub") }, "n-0"},
+		"expired":    {func(c map[string]interface{}) { c["exp"] = time.Now().Add(-2 * time.Minute).Unix() }, "n-0"},
+		"future iat": {func(c map[string]interface{}) { c["iat"] = time.Now().Add(time.Hour).Unix() }, "n-0"},
+		"nonce":      {func(map[string]interface{}) {}, "n-1"},
+	} {
+		claims := fake.Claims("1", "n-0")
+		tc.change(claims)
+
+		_, err := p.Verify(ctx, fake.Sign(claims), tc.nonce)
+		assert.ErrorIs(err, ErrInvalidToken, name)
+	}
+
+	_, err = p.Verify(ctx, stranger.Sign(valid), "n-0")
+	assert.ErrorContains(err, "bad signature")
+
+	token := fake.Sign(valid)
+	unsigned := "eyJhbGciOiJub25lIn0." + strings.Split(token, ".")[1] + "."
+	_, err = p.Verify(ctx, unsigned, "n-0")
+	assert.ErrorContains(err, `unsupported algorithm "none"`)
+
+	_, err = p.Verify(ctx, "not.a-token", "n-0")
+	assert.ErrorIs(err, ErrInvalidToken)
+}
+
+func TestKeyRefresh(t *testing.T) {
+	assert := assert.New(t)
+	fake := oidctest.NewProvider("mock-store", "secret")
+	defer fake.Close()
+	p := discover(t, fake)
+	assert.Equal(1, fake.KeyFetches())
+
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"other","typ":"JWT"}`))
+	token := header + "." + strings.SplitN(fake.Sign(fake.Claims("1", "n-0")), ".", 2)[1]
+
+	for i := 0; i < 3; i++ {
+		_, err := p.Verify(ctx, token, "n-0")
+		assert.ErrorContains(err, `unknown signing key "other"`)
+	}
+	assert.Equal(1, fake.KeyFetches(), "keys fetched at discovery are not fetched again")
+
+	now = now.Add(keyRefreshInterval)
+	for i := 0; i < 3; i++ {
+		_, err := p.Verify(ctx, token, "n-0")
+		assert.ErrorContains(err, `unknown signing key "other"`)
+	}
+	assert.Equal(2, fake.KeyFetches(), "unknown key ids fetch the keys once a minute")
+
+	_, err := p.Verify(ctx, fake.Sign(fake.Claims("1", "n-0")), "n-0")
+	assert.Nil(err)
+}
+
+func TestRole(t *testing.T) {
+	assert := assert.New(t)
+	p := &Provider{
+		roleClaim: "groups",
+		roles:     map[string]user.Role{"store-clerks": user.Clerk, "store-admins": user.Admin},
+	}
+	claims := func(groups interface{}) Claims {
+		return Claims{raw: map[string]interface{}{"groups": groups}}
+	}
+
+	role, ok := p.Role(claims([]interface{}{"store-admins", "store-clerks"}))
+	assert.True(ok)
+	assert.Equal(user.Admin, role)
+
+	role, _ = p.Role(claims("store-clerks"))
+	assert.Equal(user.Clerk, role)
+
+	_, ok = p.Role(claims([]interface{}{"staff"}))
+	assert.False(ok)
+
+	p.defaultRole = user.Viewer
+	role, ok = p.Role(claims(nil))
+	assert.True(ok)
+	assert.Equal(user.Viewer, role)
+
+	assert.Equal("ana.silva", Username(Claims{Email: "Ana.Silva@example.com"}))
+}
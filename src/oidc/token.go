@@ -0,0 +1,268 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken means an ID token failed verification.
+var ErrInvalidToken = errors.New("invalid id token")
+
+// leeway is how far the provider's clock may be from ours.
+const leeway = time.Minute
+
+// keyRefreshInterval is the least time between two fetches of the JWK set
+// prompted by unknown key ids, which anyone can put in a token.
+const keyRefreshInterval = time.Minute
+
+// Claims are the verified claims of an ID token.
+type Claims struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	PreferredUsername string
+	Expiry            time.Time
+	IssuedAt          time.Time
+
+	raw map[string]interface{}
+}
+
+// Strings returns claim name as a list of strings, whether the token holds
+// one string or an array of them.
+func (c Claims) Strings(name string) []string {
+	switch v := c.raw[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := []string{}
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
+
+// payload is the wire form of the claims Verify checks.
+type payload struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	AuthorizedParty   string   `json:"azp"`
+	Expiry            float64  `json:"exp"`
+	IssuedAt          float64  `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	PreferredUsername string   `json:"preferred_username"`
+}
+
+// audience is the aud claim, which may be one string or an array.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a audience) contains(s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Verify checks that raw is an ID token the provider signed with RS256 for
+// this client, for the login that sent nonce, and still fresh.
+func (p *Provider) Verify(ctx context.Context, raw, nonce string) (Claims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("%w: not a JWS compact serialization", ErrInvalidToken)
+	}
+
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	if header.Alg != "RS256" {
+		return Claims{}, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Alg)
+	}
+
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return Claims{}, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	pl := payload{}
+	c := Claims{}
+	if err := decodeSegment(parts[1], &pl); err != nil {
+		return Claims{}, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	if err := decodeSegment(parts[1], &c.raw); err != nil {
+		return Claims{}, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+
+	now := p.now()
+	c.Issuer, c.Subject = pl.Issuer, pl.Subject
+	c.Email, c.PreferredUsername = pl.Email, pl.PreferredUsername
+	c.Expiry, c.IssuedAt = unixTime(pl.Expiry), unixTime(pl.IssuedAt)
+
+	switch {
+	case pl.Issuer != p.issuer:
+		return Claims{}, fmt.Errorf("%w: issued by %q, not %q", ErrInvalidToken, pl.Issuer, p.issuer)
+	case !pl.Audience.contains(p.clientID):
+		return Claims{}, fmt.Errorf("%w: not issued for this client", ErrInvalidToken)
+	case len(pl.Audience) > 1 && pl.AuthorizedParty != p.clientID:
+		return Claims{}, fmt.Errorf("%w: authorized party is %q", ErrInvalidToken, pl.AuthorizedParty)
+	case pl.Subject == "":
+		return Claims{}, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	case pl.Expiry == 0 || !now.Before(c.Expiry.Add(leeway)):
+		return Claims{}, fmt.Errorf("%w: expired", ErrInvalidToken)
+	case c.IssuedAt.After(now.Add(leeway)):
+		return Claims{}, fmt.Errorf("%w: issued in the future", ErrInvalidToken)
+	case subtle.ConstantTimeCompare([]byte(pl.Nonce), []byte(nonce)) != 1:
+		return Claims{}, fmt.Errorf("%w: nonce does not match this login", ErrInvalidToken)
+	}
+
+	return c, nil
+}
+
+// key returns the signing key named kid, fetching the provider's keys
+// again when it is unknown, since providers rotate them. They are fetched at
+// most once per keyRefreshInterval; until then unknown ids are rejected.
+func (p *Provider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	if k, ok := p.lookup(kid); ok {
+		return k, nil
+	}
+
+	if p.refreshDue() {
+		if err := p.refreshKeys(ctx); err != nil {
+			return nil, err
+		}
+		if k, ok := p.lookup(kid); ok {
+			return k, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+}
+
+// refreshDue reports whether the keys may be fetched again, and if so
+// claims the fetch, so concurrent callers do not all make it.
+func (p *Provider) refreshDue() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	if now.Sub(p.fetched) < keyRefreshInterval {
+		return false
+	}
+	p.fetched = now
+
+	return true
+}
+
+func (p *Provider) lookup(kid string) (*rsa.PublicKey, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+// refreshKeys replaces the known signing keys with the RSA signing keys of
+// the provider's JWK set.
+func (p *Provider) refreshKeys(ctx context.Context) error {
+	set := struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	if err := p.getJSON(ctx, p.jwksURL, &set); err != nil {
+		return err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return fmt.Errorf("%w: malformed RSA key %q", ErrProvider, k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("%w: no RSA signing keys at %s", ErrProvider, p.jwksURL)
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.fetched = p.now()
+	p.mu.Unlock()
+
+	return nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+func unixTime(secs float64) time.Time {
+	return time.Unix(0, int64(secs*float64(time.Second)))
+}
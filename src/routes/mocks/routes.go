@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	http "net/http"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,9 +35,11 @@ func (m *MockRouterService) EXPECT() *MockRouterServiceMockRecorder {
 }
 
 // LoadRoutes mocks base method.
-func (m *MockRouterService) LoadRoutes() {
+func (m *MockRouterService) LoadRoutes() http.Handler {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "LoadRoutes")
+	ret := m.ctrl.Call(m, "LoadRoutes")
+	ret0, _ := ret[0].(http.Handler)
+	return ret0
 }
 
 // LoadRoutes indicates an expected call of LoadRoutes.
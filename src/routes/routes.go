@@ -1,9 +1,15 @@
 package routes
 
 import (
+	"log/slog"
 	"net/http"
+	"strings"
 
 	ctl "github.com/silastgoes/mock-store/src/controllers"
+	"github.com/silastgoes/mock-store/src/metrics"
+	"github.com/silastgoes/mock-store/src/middleware"
+	"github.com/silastgoes/mock-store/src/model/apikey"
+	"github.com/silastgoes/mock-store/src/model/user"
 )
 
 var (
@@ -11,25 +17,115 @@ var (
 )
 
 type router struct {
-	pcs ctl.ProductControlService
+	pcs      ctl.ProductControlService
+	api      ctl.ProductApiControlService
+	health   ctl.HealthControlService
+	auth     ctl.AuthControlService
+	sso      ctl.OIDCControlService
+	apiKeys  ctl.APIKeyControlService
+	keys     apikey.APIKeyModelService
+	sessions *middleware.Sessions
+	metrics  *metrics.Metrics
+	logger   *slog.Logger
 }
 
 //go:generate mockgen --source=routes.go --package=mocks --destination=./mocks/routes.go  RouterService
 type RouterService interface {
-	LoadRoutes()
+	LoadRoutes() http.Handler
 }
 
-func NewRouterService(controller ctl.ProductControlService) *router {
+func NewRouterService(controller ctl.ProductControlService, api ctl.ProductApiControlService, health ctl.HealthControlService, auth ctl.AuthControlService, sso ctl.OIDCControlService, apiKeys ctl.APIKeyControlService, keys apikey.APIKeyModelService, sessions *middleware.Sessions, m *metrics.Metrics, logger *slog.Logger) *router {
 	return &router{
-		pcs: controller,
+		pcs:      controller,
+		api:      api,
+		health:   health,
+		auth:     auth,
+		sso:      sso,
+		apiKeys:  apiKeys,
+		keys:     keys,
+		sessions: sessions,
+		metrics:  m,
+		logger:   logger,
 	}
 }
 
-func (r *router) LoadRoutes() {
-	http.HandleFunc("/", r.pcs.Index)
-	http.HandleFunc("/new", r.pcs.New)
-	http.HandleFunc("/insert", r.pcs.Insert)
-	http.HandleFunc("/delete", r.pcs.Delete)
-	http.HandleFunc("/edit", r.pcs.Edit)
-	http.HandleFunc("/update", r.pcs.Update)
+// LoadRoutes builds a handler serving every route behind the request id,
+// tracing, access log, panic recovery, API key, CSRF, session and audit
+// middleware. The product pages need a signed-in user whose role allows the
+// action; editing needs only EditQuantity, and the controller checks the
+// rest. The JSON API needs an API key. Single sign-on is served when sso is
+// not nil. Requests for a known path with
+// another method are answered with 405 and an Allow header. Every route is
+// counted and timed under its pattern on /metrics.
+func (r *router) LoadRoutes() http.Handler {
+	mux := http.NewServeMux()
+	handle := func(pattern string, h http.HandlerFunc) {
+		mux.Handle(pattern, r.metrics.Instrument(pattern, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			middleware.NameSpan(req, pattern)
+			h(w, req)
+		})))
+	}
+	allow := func(p user.Permission, h http.HandlerFunc) http.HandlerFunc {
+		return middleware.Authorize(p, r.logger)(h).ServeHTTP
+	}
+
+	handle("GET /{$}", allow(user.ViewProducts, r.pcs.Index))
+	handle("GET /products/new", allow(user.CreateProducts, r.pcs.New))
+	handle("POST /products", allow(user.CreateProducts, r.pcs.Insert))
+	handle("GET /products/{id}/edit", allow(user.EditQuantity, r.pcs.Edit))
+	handle("PUT /products/{id}", allow(user.EditQuantity, r.pcs.Update))
+	handle("DELETE /products/{id}", allow(user.DeleteProducts, r.pcs.Delete))
+	handle("GET /products/{id}/history", allow(user.ViewProducts, r.pcs.History))
+
+	handle("GET /login", r.auth.LoginPage)
+	handle("POST /login", r.auth.Login)
+	handle("POST /logout", r.auth.Logout)
+	if r.sso != nil {
+		handle("GET /login/oidc", r.sso.Start)
+		handle("GET /login/oidc/callback", r.sso.Callback)
+	}
+
+	handle("GET /admin/api-keys", allow(user.ManageAPIKeys, r.apiKeys.List))
+	handle("POST /admin/api-keys", allow(user.ManageAPIKeys, r.apiKeys.Create))
+	handle("DELETE /admin/api-keys/{id}", allow(user.ManageAPIKeys, r.apiKeys.Delete))
+
+	handle("GET /api/v1/products", r.api.List)
+	handle("POST /api/v1/products", r.api.Create)
+	handle("GET /api/v1/products/{id}", r.api.Get)
+	handle("PUT /api/v1/products/{id}", r.api.Replace)
+	handle("PATCH /api/v1/products/{id}", r.api.Patch)
+	handle("DELETE /api/v1/products/{id}", r.api.Delete)
+	handle("GET /api/v1/products/{id}/history", r.api.History)
+
+	handle("GET /healthz", r.health.Healthz)
+	handle("GET /readyz", r.health.Readyz)
+	handle("GET /status", r.health.Status)
+	handle("GET /metrics", r.metrics.Handler().ServeHTTP)
+
+	return middleware.Chain(methodOverride(mux),
+		middleware.RequestID,
+		middleware.Trace,
+		middleware.AccessLog(r.logger),
+		middleware.Recover(r.logger),
+		middleware.APIKeys(r.keys, r.logger),
+		middleware.CSRF(r.logger),
+		r.sessions.Load,
+		middleware.Audit,
+	)
+}
+
+// methodOverride lets HTML forms, which can only POST, reach PUT and DELETE
+// routes by naming the method in a hidden "_method" field.
+func methodOverride(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost &&
+			strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			switch method := strings.ToUpper(req.PostFormValue("_method")); method {
+			case http.MethodPut, http.MethodDelete:
+				req.Method = method
+			}
+		}
+
+		next.ServeHTTP(w, req)
+	})
 }
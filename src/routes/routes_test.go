@@ -1,24 +1,467 @@
 package routes
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/silastgoes/mock-store/src/controllers/mocks"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/silastgoes/mock-store/src/metrics"
+	"github.com/silastgoes/mock-store/src/middleware"
+	"github.com/silastgoes/mock-store/src/model/apikey"
+	"github.com/silastgoes/mock-store/src/model/product"
+	"github.com/silastgoes/mock-store/src/model/user"
+	"github.com/stretchr/testify/assert"
 )
 
+// recordId is a controller stand-in that reports the {id} path value it saw.
+func recordId(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(r.PathValue("id")))
+}
+
+// csrfToken is a well-formed token for requests that must pass middleware.CSRF.
+var csrfToken = strings.Repeat("A", 43)
+
+// withCSRF makes req carry a matching CSRF cookie and header.
+func withCSRF(req *http.Request) *http.Request {
+	req.AddCookie(&http.Cookie{Name: middleware.CSRFCookie, Value: csrfToken})
+	req.Header.Set(middleware.CSRFHeader, csrfToken)
+	return req
+}
+
+// fixture is a router over controller mocks, with real sessions and the
+// cookie of a user with role signed in to them, and real API keys with the
+// tokens of a read and a write key.
+type fixture struct {
+	srv     *mocks.MockProductControlService
+	api     *mocks.MockProductApiControlService
+	health  *mocks.MockHealthControlService
+	auth    *mocks.MockAuthControlService
+	sso     *mocks.MockOIDCControlService
+	apiKeys *mocks.MockAPIKeyControlService
+	keys    apikey.APIKeyModelService
+	handler http.Handler
+	session *http.Cookie
+	read    string
+	write   string
+}
+
+func newFixture(t *testing.T, ctrl *gomock.Controller, role user.Role) fixture {
+	t.Helper()
+
+	users := user.NewMemoryUserModelService()
+	id, err := users.Create(context.Background(), "ana", "correct horse", role)
+	assert.Nil(t, err)
+
+	sessions := middleware.NewSessions(users, []byte(strings.Repeat("k", 32)), time.Hour, logging.Discard())
+	w := httptest.NewRecorder()
+	assert.Nil(t, sessions.Start(w, httptest.NewRequest(http.MethodPost, "/login", nil), user.User{Id: id, Username: "ana"}))
+
+	keys := apikey.NewMemoryAPIKeyModelService()
+	_, read, err := keys.Create(context.Background(), "reports", apikey.Read, nil, id)
+	assert.Nil(t, err)
+	_, write, err := keys.Create(context.Background(), "sync", apikey.Write, nil, id)
+	assert.Nil(t, err)
+
+	f := fixture{
+		srv:     mocks.NewMockProductControlService(ctrl),
+		api:     mocks.NewMockProductApiControlService(ctrl),
+		health:  mocks.NewMockHealthControlService(ctrl),
+		auth:    mocks.NewMockAuthControlService(ctrl),
+		sso:     mocks.NewMockOIDCControlService(ctrl),
+		apiKeys: mocks.NewMockAPIKeyControlService(ctrl),
+		keys:    keys,
+		session: w.Result().Cookies()[0],
+		read:    read,
+		write:   write,
+	}
+	f.handler = NewRouterService(f.srv, f.api, f.health, f.auth, f.sso, f.apiKeys, keys, sessions, metrics.New(logging.Discard()), logging.Discard()).LoadRoutes()
+
+	return f
+}
+
+func withBearer(req *http.Request, token string) *http.Request {
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
 func TestLoadRoutes(t *testing.T) {
 	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	f := newFixture(t, ctrl, user.Admin)
+	srv, handler := f.srv, f.handler
+
+	srv.EXPECT().Index(gomock.Any(), gomock.Any()).Return()
+	srv.EXPECT().New(gomock.Any(), gomock.Any()).Return()
+	srv.EXPECT().Insert(gomock.Any(), gomock.Any()).Return()
+	srv.EXPECT().Edit(gomock.Any(), gomock.Any()).Do(recordId)
+	srv.EXPECT().Update(gomock.Any(), gomock.Any()).Do(recordId).Times(2)
+	srv.EXPECT().Delete(gomock.Any(), gomock.Any()).Do(recordId).Times(2)
+	srv.EXPECT().History(gomock.Any(), gomock.Any()).Do(recordId)
+
+	for _, tc := range []struct {
+		method, target, form, id string
+	}{
+		{http.MethodGet, "/", "", ""},
+		{http.MethodGet, "/products/new", "", ""},
+		{http.MethodPost, "/products", "name=pen", ""},
+		{http.MethodGet, "/products/7/edit", "", "7"},
+		{http.MethodPut, "/products/7", "", "7"},
+		{http.MethodPost, "/products/7", "_method=put", "7"},
+		{http.MethodDelete, "/products/7", "", "7"},
+		{http.MethodPost, "/products/7", "_method=DELETE", "7"},
+		{http.MethodGet, "/products/7/history", "", "7"},
+	} {
+		req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.form))
+		if tc.form != "" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.AddCookie(f.session)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, withCSRF(req))
+
+		assert.Equal(http.StatusOK, w.Code, tc.method+" "+tc.target)
+		assert.Equal(tc.id, w.Body.String(), tc.method+" "+tc.target)
+	}
+}
+
+func TestRejectedRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	f := newFixture(t, ctrl, user.Admin)
+	handler := f.handler
+
+	for _, tc := range []struct {
+		method, target string
+		status         int
+	}{
+		{http.MethodGet, "/delete?id=1", http.StatusNotFound},
+		{http.MethodGet, "/missing", http.StatusNotFound},
+		{http.MethodGet, "/products/1", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/products/1", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/products", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/api/v1/products", http.StatusMethodNotAllowed},
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, withBearer(withCSRF(httptest.NewRequest(tc.method, tc.target, nil)), f.write))
+
+		assert.Equal(tc.status, w.Code, tc.method+" "+tc.target)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, withBearer(httptest.NewRequest(http.MethodDelete, "/api/v1/products", nil), f.write))
+	assert.Equal("GET, HEAD, POST", w.Header().Get("Allow"))
+	assert.NotEmpty(w.Header().Get("X-Request-ID"))
+}
+
+func TestApiRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	f := newFixture(t, ctrl, user.Admin)
+	api, handler := f.api, f.handler
+
+	api.EXPECT().List(gomock.Any(), gomock.Any()).Return()
+	api.EXPECT().Create(gomock.Any(), gomock.Any()).Return()
+	api.EXPECT().Get(gomock.Any(), gomock.Any()).Do(recordId)
+	api.EXPECT().Replace(gomock.Any(), gomock.Any()).Do(recordId)
+	api.EXPECT().Patch(gomock.Any(), gomock.Any()).Do(recordId)
+	api.EXPECT().Delete(gomock.Any(), gomock.Any()).Do(recordId)
+	api.EXPECT().History(gomock.Any(), gomock.Any()).Do(recordId)
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, withBearer(httptest.NewRequest(method, "/api/v1/products", nil), f.write))
+		assert.Equal(http.StatusOK, w.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, withBearer(httptest.NewRequest(method, "/api/v1/products/1", nil), f.write))
+		assert.Equal("1", w.Body.String(), method)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, withBearer(httptest.NewRequest(http.MethodGet, "/api/v1/products/1/history", nil), f.read))
+	assert.Equal("1", w.Body.String())
+}
+
+func TestAuditRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	f := newFixture(t, ctrl, user.Admin)
+	recordActor := func(w http.ResponseWriter, r *http.Request) {
+		a := product.ActorFrom(r.Context())
+		w.Write([]byte(a.Name + " " + a.RequestId))
+	}
+	f.srv.EXPECT().Delete(gomock.Any(), gomock.Any()).Do(recordActor)
+	f.api.EXPECT().Delete(gomock.Any(), gomock.Any()).Do(recordActor)
+
+	req := httptest.NewRequest(http.MethodDelete, "/products/7", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+	req.AddCookie(f.session)
+	w := httptest.NewRecorder()
+	f.handler.ServeHTTP(w, withCSRF(req))
+	assert.Equal("user:ana req-1", w.Body.String())
+
+	req = withBearer(httptest.NewRequest(http.MethodDelete, "/api/v1/products/7", nil), f.write)
+	req.Header.Set(middleware.RequestIDHeader, "req-2")
+	w = httptest.NewRecorder()
+	f.handler.ServeHTTP(w, req)
+	name, id, _ := strings.Cut(w.Body.String(), " ")
+	assert.Regexp(`^api_key:[\w-]+$`, name)
+	assert.True(strings.HasPrefix(f.write, apikey.TokenPrefix+strings.TrimPrefix(name, "api_key:")), name)
+	assert.Equal("req-2", id)
+}
+
+func TestHealthRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	f := newFixture(t, ctrl, user.Admin)
+	health, handler := f.health, f.handler
+
+	health.EXPECT().Healthz(gomock.Any(), gomock.Any()).Return()
+	health.EXPECT().Readyz(gomock.Any(), gomock.Any()).Return()
+	health.EXPECT().Status(gomock.Any(), gomock.Any()).Return()
+
+	for _, target := range []string{"/healthz", "/readyz", "/status"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(http.StatusOK, w.Code, target)
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, withCSRF(httptest.NewRequest(http.MethodPost, target, nil)))
+		assert.Equal(http.StatusMethodNotAllowed, w.Code, target)
+	}
+}
+
+func TestCSRFRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	f := newFixture(t, ctrl, user.Admin)
+	srv, api, handler := f.srv, f.api, f.handler
+
+	for _, tc := range []struct {
+		method, target, form string
+	}{
+		{http.MethodPost, "/products", "name=pen"},
+		{http.MethodPost, "/products/7", "_method=DELETE"},
+		{http.MethodPost, "/products/7", "_method=DELETE&csrf_token=" + csrfToken},
+	} {
+		req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(http.StatusForbidden, w.Code, tc.form)
+	}
+
+	srv.EXPECT().Delete(gomock.Any(), gomock.Any()).Do(recordId)
+	req := httptest.NewRequest(http.MethodPost, "/products/7", strings.NewReader("_method=DELETE&csrf_token="+csrfToken))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: middleware.CSRFCookie, Value: csrfToken})
+	req.AddCookie(f.session)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal("7", w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, withCSRF(httptest.NewRequest(http.MethodPost, "/api/v1/products", nil)))
+	assert.Equal(http.StatusUnauthorized, w.Code)
+
+	api.EXPECT().Create(gomock.Any(), gomock.Any()).Return()
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, withBearer(httptest.NewRequest(http.MethodPost, "/api/v1/products", nil), f.write))
+	assert.Equal(http.StatusOK, w.Code)
+}
+
+func TestAuthRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	f := newFixture(t, ctrl, user.Admin)
+	handler := f.handler
+
+	f.auth.EXPECT().LoginPage(gomock.Any(), gomock.Any()).Return()
+	f.auth.EXPECT().Login(gomock.Any(), gomock.Any()).Return()
+	f.auth.EXPECT().Logout(gomock.Any(), gomock.Any()).Return()
+
+	for _, tc := range []struct{ method, target string }{
+		{http.MethodGet, "/login"},
+		{http.MethodPost, "/login"},
+		{http.MethodPost, "/logout"},
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, withCSRF(httptest.NewRequest(tc.method, tc.target, nil)))
+		assert.Equal(http.StatusOK, w.Code, tc.method+" "+tc.target)
+	}
+
+	t.Run("Testing single sign-on", func(t *testing.T) {
+		f.sso.EXPECT().Start(gomock.Any(), gomock.Any()).Return()
+		f.sso.EXPECT().Callback(gomock.Any(), gomock.Any()).Return()
+
+		for _, target := range []string{"/login/oidc?next=%2F", "/login/oidc/callback?code=c&state=s"} {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+			assert.Equal(http.StatusOK, w.Code, target)
+		}
+
+		users := user.NewMemoryUserModelService()
+		sessions := middleware.NewSessions(users, []byte(strings.Repeat("k", 32)), time.Hour, logging.Discard())
+		off := NewRouterService(f.srv, f.api, f.health, f.auth, nil, f.apiKeys, f.keys, sessions, metrics.New(logging.Discard()), logging.Discard()).LoadRoutes()
+		w := httptest.NewRecorder()
+		off.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login/oidc", nil))
+		assert.Equal(http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Testing product pages need a session", func(t *testing.T) {
+		forged := *f.session
+		forged.Value = strings.Replace(forged.Value, ".", ".x", 1)
+
+		for _, cookie := range []*http.Cookie{nil, &forged} {
+			req := httptest.NewRequest(http.MethodGet, "/products/7/edit?tab=1", nil)
+			if cookie != nil {
+				req.AddCookie(cookie)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(http.StatusSeeOther, w.Code)
+			assert.Equal("/login?next=%2Fproducts%2F7%2Fedit%3Ftab%3D1", w.Header().Get("Location"))
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, withCSRF(httptest.NewRequest(http.MethodDelete, "/products/7", nil)))
+		assert.Equal(http.StatusSeeOther, w.Code)
+		assert.Equal("/login", w.Header().Get("Location"))
+	})
+}
+
+func TestRoutePermissions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	routes := []struct{ method, target string }{
+		{http.MethodGet, "/"},
+		{http.MethodGet, "/products/new"},
+		{http.MethodPost, "/products"},
+		{http.MethodGet, "/products/7/edit"},
+		{http.MethodPut, "/products/7"},
+		{http.MethodDelete, "/products/7"},
+	}
+
+	for _, tc := range []struct {
+		role    user.Role
+		allowed []bool
+	}{
+		{user.Viewer, []bool{true, false, false, false, false, false}},
+		{user.Clerk, []bool{true, false, false, true, true, false}},
+		{user.Manager, []bool{true, true, true, true, true, true}},
+	} {
+		f := newFixture(t, ctrl, tc.role)
+		f.srv.EXPECT().Index(gomock.Any(), gomock.Any()).AnyTimes()
+		f.srv.EXPECT().New(gomock.Any(), gomock.Any()).AnyTimes()
+		f.srv.EXPECT().Insert(gomock.Any(), gomock.Any()).AnyTimes()
+		f.srv.EXPECT().Edit(gomock.Any(), gomock.Any()).AnyTimes()
+		f.srv.EXPECT().Update(gomock.Any(), gomock.Any()).AnyTimes()
+		f.srv.EXPECT().Delete(gomock.Any(), gomock.Any()).AnyTimes()
+
+		for i, route := range routes {
+			req := withCSRF(httptest.NewRequest(route.method, route.target, nil))
+			req.AddCookie(f.session)
+			w := httptest.NewRecorder()
+			f.handler.ServeHTTP(w, req)
+
+			want := http.StatusForbidden
+			if tc.allowed[i] {
+				want = http.StatusOK
+			}
+			assert.Equal(want, w.Code, string(tc.role)+" "+route.method+" "+route.target)
+		}
+	}
+}
+
+func TestAPIKeyRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+
+	f := newFixture(t, ctrl, user.Admin)
+	handler := f.handler
+
+	t.Run("Testing the API needs a live key", func(t *testing.T) {
+		for _, token := range []string{"", apikey.TokenPrefix + "forged"} {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/products", nil)
+			if token != "" {
+				withBearer(req, token)
+			}
+			req.AddCookie(f.session)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(http.StatusUnauthorized, w.Code, token)
+			assert.Contains(w.Header().Get("WWW-Authenticate"), "Bearer")
+			assert.Equal("application/json", w.Header().Get("Content-Type"))
+		}
+
+		assert.Nil(f.keys.Delete(context.Background(), "1"))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, withBearer(httptest.NewRequest(http.MethodGet, "/api/v1/products", nil), f.read))
+		assert.Equal(http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Testing scopes", func(t *testing.T) {
+		f := newFixture(t, ctrl, user.Admin)
+		f.api.EXPECT().List(gomock.Any(), gomock.Any()).Return()
+
+		w := httptest.NewRecorder()
+		f.handler.ServeHTTP(w, withBearer(httptest.NewRequest(http.MethodGet, "/api/v1/products", nil), f.read))
+		assert.Equal(http.StatusOK, w.Code)
+
+		for _, method := range []string{http.MethodPost, http.MethodDelete} {
+			w := httptest.NewRecorder()
+			f.handler.ServeHTTP(w, withBearer(httptest.NewRequest(method, "/api/v1/products", nil), f.read))
+			assert.Equal(http.StatusForbidden, w.Code, method)
+		}
+
+		k, err := f.keys.Authenticate(context.Background(), f.read)
+		assert.Nil(err)
+		assert.NotNil(k.LastUsedAt)
+	})
 
-	srv := mocks.NewMockProductControlService(ctrl)
-	rs := NewRouterService(srv)
+	t.Run("Testing the admin page needs ManageAPIKeys", func(t *testing.T) {
+		f.apiKeys.EXPECT().List(gomock.Any(), gomock.Any()).Return()
+		f.apiKeys.EXPECT().Create(gomock.Any(), gomock.Any()).Return()
+		f.apiKeys.EXPECT().Delete(gomock.Any(), gomock.Any()).Do(recordId)
 
-	srv.EXPECT().Index(gomock.Any(), gomock.Any()).Return().AnyTimes()
-	srv.EXPECT().New(gomock.Any(), gomock.Any()).Return().AnyTimes()
-	srv.EXPECT().Insert(gomock.Any(), gomock.Any()).Return().AnyTimes()
-	srv.EXPECT().Delete(gomock.Any(), gomock.Any()).Return().AnyTimes()
-	srv.EXPECT().Edit(gomock.Any(), gomock.Any()).Return().AnyTimes()
-	srv.EXPECT().Update(gomock.Any(), gomock.Any()).Return().AnyTimes()
+		for _, tc := range []struct{ method, target, id string }{
+			{http.MethodGet, "/admin/api-keys", ""},
+			{http.MethodPost, "/admin/api-keys", ""},
+			{http.MethodDelete, "/admin/api-keys/3", "3"},
+		} {
+			req := withCSRF(httptest.NewRequest(tc.method, tc.target, nil))
+			req.AddCookie(f.session)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(http.StatusOK, w.Code, tc.method+" "+tc.target)
+			assert.Equal(tc.id, w.Body.String())
+		}
 
-	rs.LoadRoutes()
+		manager := newFixture(t, ctrl, user.Manager)
+		req := httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil)
+		req.AddCookie(manager.session)
+		w := httptest.NewRecorder()
+		manager.handler.ServeHTTP(w, req)
+		assert.Equal(http.StatusForbidden, w.Code)
+	})
 }
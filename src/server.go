@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/config"
+	"github.com/silastgoes/mock-store/src/logging"
+)
+
+// newServer builds the HTTP server from the http section of the config.
+func newServer(cfg config.HTTP, handler http.Handler, logger *slog.Logger) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+}
+
+// Serve runs srv until it fails or ctx is done. It then stops accepting
+// connections and waits up to drain for in-flight requests to finish.
+func Serve(ctx context.Context, srv *http.Server, drain time.Duration, logger *slog.Logger) error {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	return serve(ctx, srv, ln, drain, logger)
+}
+
+func serve(ctx context.Context, srv *http.Server, ln net.Listener, drain time.Duration, logger *slog.Logger) error {
+	failed := make(chan error, 1)
+	go func() {
+		failed <- srv.Serve(ln)
+	}()
+	logger.Info(logging.ServerStarted, "addr", ln.Addr().String())
+
+	select {
+	case err := <-failed:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info(logging.ServerStopping, "drain", drain)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return err
+	}
+
+	if err := <-failed; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	logger.Info(logging.ServerStopped)
+	return nil
+}
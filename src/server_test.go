@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/silastgoes/mock-store/src/config"
+	"github.com/silastgoes/mock-store/src/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewServer(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := config.Default().HTTP
+	cfg.Addr = "127.0.0.1:8080"
+	cfg.ReadTimeout = 3 * time.Second
+	srv := newServer(cfg, http.NotFoundHandler(), logging.Discard())
+
+	assert.Equal("127.0.0.1:8080", srv.Addr)
+	assert.Equal(3*time.Second, srv.ReadTimeout)
+	assert.Equal(cfg.WriteTimeout, srv.WriteTimeout)
+	assert.Equal(cfg.IdleTimeout, srv.IdleTimeout)
+	assert.Equal(cfg.ReadHeaderTimeout, srv.ReadHeaderTimeout)
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	assert := assert.New(t)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(err)
+
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, srv, ln, time.Second, logging.Discard())
+	}()
+
+	body := make(chan string, 1)
+	go func() {
+		res, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		body <- string(b)
+	}()
+
+	<-started
+	stop()
+	time.Sleep(50 * time.Millisecond)
+
+	_, err = net.DialTimeout("tcp", ln.Addr().String(), 100*time.Millisecond)
+	assert.Error(err, "new connections are refused while draining")
+
+	close(release)
+	assert.Equal("done", <-body)
+	assert.Nil(<-served)
+}
+
+func TestServeGivesUpAfterDrain(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	started := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, srv, ln, 50*time.Millisecond, logging.Discard())
+	}()
+	go http.Get("http://" + ln.Addr().String())
+
+	<-started
+	stop()
+
+	assert.ErrorIs(t, <-served, context.DeadlineExceeded)
+}
+
+func TestServeReportsListenErrors(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	srv := &http.Server{Addr: ln.Addr().String()}
+	err = Serve(context.Background(), srv, time.Second, logging.Discard())
+
+	assert.Error(t, err)
+}
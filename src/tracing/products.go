@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/silastgoes/mock-store/src/model/product"
+	"github.com/silastgoes/mock-store/src/money"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName names the instrumentation scope of the spans started here.
+const tracerName = "github.com/silastgoes/mock-store/src/tracing"
+
+// tracedProducts opens a child span around every call to the wrapped store.
+type tracedProducts struct {
+	next   product.ProductModelService
+	system string
+}
+
+// Products wraps svc so each of its methods shows up as a span named
+// "product.<Method>" under the request's span. system is the database
+// behind svc, such as "postgresql", or "" for the memory store.
+func Products(svc product.ProductModelService, system string) product.ProductModelService {
+	return &tracedProducts{next: svc, system: system}
+}
+
+func (tp *tracedProducts) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, semconv.DBOperationName(method))
+	if tp.system != "" {
+		attrs = append(attrs, semconv.DBSystemKey.String(tp.system))
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, "product."+method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// end closes span, deferred so err points at the call's final error. Like
+// the metrics, missing rows and rejected input do not mark the span failed.
+func end(span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(*err)
+		if !errors.Is(*err, product.ErrNotFound) && !errors.Is(*err, product.ErrInvalid) {
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+	}
+	span.End()
+}
+
+func (tp *tracedProducts) Create(ctx context.Context, name, description string, value money.Money, quantity int) (id int, err error) {
+	ctx, span := tp.start(ctx, "Create")
+	defer end(span, &err)
+
+	id, err = tp.next.Create(ctx, name, description, value, quantity)
+	span.SetAttributes(attribute.Int("product.id", id))
+	return id, err
+}
+
+func (tp *tracedProducts) Get(ctx context.Context, param string) (p product.Product, err error) {
+	ctx, span := tp.start(ctx, "Get", attribute.String("product.id", param))
+	defer end(span, &err)
+
+	return tp.next.Get(ctx, param)
+}
+
+func (tp *tracedProducts) ListProducts(ctx context.Context, opts product.ListOptions) (page product.Page, err error) {
+	ctx, span := tp.start(ctx, "ListProducts")
+	defer end(span, &err)
+
+	page, err = tp.next.ListProducts(ctx, opts)
+	span.SetAttributes(attribute.Int("product.count", len(page.Products)))
+	return page, err
+}
+
+func (tp *tracedProducts) Update(ctx context.Context, id int, name, description string, value money.Money, quantity int) (err error) {
+	ctx, span := tp.start(ctx, "Update", attribute.Int("product.id", id))
+	defer end(span, &err)
+
+	return tp.next.Update(ctx, id, name, description, value, quantity)
+}
+
+func (tp *tracedProducts) SetQuantity(ctx context.Context, id int, quantity int) (err error) {
+	ctx, span := tp.start(ctx, "SetQuantity", attribute.Int("product.id", id))
+	defer end(span, &err)
+
+	return tp.next.SetQuantity(ctx, id, quantity)
+}
+
+func (tp *tracedProducts) Delete(ctx context.Context, id string) (err error) {
+	ctx, span := tp.start(ctx, "Delete", attribute.String("product.id", id))
+	defer end(span, &err)
+
+	return tp.next.Delete(ctx, id)
+}
+
+func (tp *tracedProducts) Inventory(ctx context.Context) (inv product.Inventory, err error) {
+	ctx, span := tp.start(ctx, "Inventory")
+	defer end(span, &err)
+
+	return tp.next.Inventory(ctx)
+}
+
+func (tp *tracedProducts) History(ctx context.Context, id string) (entries []product.AuditEntry, err error) {
+	ctx, span := tp.start(ctx, "History", attribute.String("product.id", id))
+	defer end(span, &err)
+
+	entries, err = tp.next.History(ctx, id)
+	span.SetAttributes(attribute.Int("product.audit.count", len(entries)))
+	return entries, err
+}
@@ -0,0 +1,137 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/silastgoes/mock-store/src/config"
+	"github.com/silastgoes/mock-store/src/model/product"
+	"github.com/silastgoes/mock-store/src/model/product/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	collector "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// record installs a global provider keeping every span in memory.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	prev := otel.GetTracerProvider()
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	return rec
+}
+
+func TestProducts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assert := assert.New(t)
+	rec := record(t)
+
+	store := mocks.NewMockProductModelService(ctrl)
+	store.EXPECT().Get(gomock.Any(), "1").Return(product.Product{Id: 1}, nil)
+	store.EXPECT().Get(gomock.Any(), "2").Return(product.Product{}, product.ErrNotFound)
+	store.EXPECT().Delete(gomock.Any(), "3").Return(errors.New("connection reset"))
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	svc := Products(store, "postgresql")
+	svc.Get(ctx, "1")
+	svc.Get(ctx, "2")
+	svc.Delete(ctx, "3")
+	parent.End()
+
+	spans := rec.Ended()
+	assert.Len(spans, 4)
+	for _, span := range spans[:3] {
+		assert.Equal(parent.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Contains(span.Attributes(), attribute.String("db.system", "postgresql"))
+	}
+
+	assert.Equal("product.Get", spans[0].Name())
+	assert.Contains(spans[0].Attributes(), attribute.String("product.id", "1"))
+	assert.Equal(codes.Unset, spans[0].Status().Code)
+	assert.Equal(codes.Unset, spans[1].Status().Code, "a missing product is not a failure")
+	assert.Len(spans[1].Events(), 1)
+	assert.Equal("product.Delete", spans[2].Name())
+	assert.Equal(codes.Error, spans[2].Status().Code)
+	assert.Equal("connection reset", spans[2].Status().Description)
+}
+
+func TestSetup(t *testing.T) {
+	assert := assert.New(t)
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	t.Run("Testing stdout exporter", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		flush, err := Setup(context.Background(), config.Tracing{Exporter: "stdout", ServiceName: "mock-store"}, "v1", buf)
+		assert.Nil(err)
+
+		_, span := otel.Tracer("test").Start(context.Background(), "hello")
+		span.End()
+		assert.Nil(flush(context.Background()))
+
+		assert.Contains(buf.String(), `"Name":"hello"`)
+		assert.Contains(buf.String(), `"Value":"mock-store"`)
+	})
+
+	t.Run("Testing OTLP exporter against a collector stand-in", func(t *testing.T) {
+		var mu sync.Mutex
+		var received []string
+		collectorSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			req := &collector.ExportTraceServiceRequest{}
+			if err := proto.Unmarshal(body, req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, r.URL.Path)
+			for _, rs := range req.ResourceSpans {
+				for _, ss := range rs.ScopeSpans {
+					for _, span := range ss.Spans {
+						received = append(received, span.Name)
+					}
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/x-protobuf")
+			resp, _ := proto.Marshal(&collector.ExportTraceServiceResponse{})
+			w.Write(resp)
+		}))
+		defer collectorSrv.Close()
+
+		cfg := config.Tracing{Exporter: "otlp", Endpoint: collectorSrv.URL + "/v1/traces", ServiceName: "mock-store"}
+		flush, err := Setup(context.Background(), cfg, "v1", io.Discard)
+		assert.Nil(err)
+
+		_, span := otel.Tracer("test").Start(context.Background(), "GET /products/{id}/edit")
+		span.End()
+		assert.Nil(flush(context.Background()))
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal([]string{"/v1/traces", "GET /products/{id}/edit"}, received)
+	})
+
+	t.Run("Testing none exporter", func(t *testing.T) {
+		flush, err := Setup(context.Background(), config.Tracing{Exporter: "none"}, "v1", io.Discard)
+		assert.Nil(err)
+		assert.Nil(flush(context.Background()))
+	})
+}
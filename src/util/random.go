@@ -3,6 +3,8 @@ package util
 import (
 	"math/rand"
 	"strings"
+
+	"github.com/silastgoes/mock-store/src/money"
 )
 
 const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
@@ -29,3 +31,8 @@ func RandomString(n int) string {
 func RandomFloat() float64 {
 	return rand.Float64()
 }
+
+// RandomPrice generate a random non-negative price in the default currency
+func RandomPrice() money.Money {
+	return money.New(int64(rand.Intn(100000)), money.DefaultCurrency)
+}